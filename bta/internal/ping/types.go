@@ -1,24 +1,18 @@
 package ping
 
 import (
-	"time"
-
 	"github.com/jspback/bingus/bta/internal/ui"
+	"github.com/jspback/bingus/internal/scan"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 )
 
-type PingResult struct {
-	IP  string
-	RTT time.Duration
-}
-
 type hostFoundMsg string
 
 type scanDoneMsg struct {
-	Hosts []string
-	Err   error
+	Summary scan.Summary
+	Err     error
 }
 
 type PingState int
@@ -29,6 +23,7 @@ type UIPingModel struct {
 	focusIndex int
 	spinner    spinner.Model
 	scanResult []string
+	summary    scan.Summary
 	quitting   bool
 	scanning   bool
 	error      error
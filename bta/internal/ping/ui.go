@@ -1,12 +1,14 @@
 package ping
 
 import (
+	"context"
 	"fmt"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jspback/bingus/bta/internal/ui"
+	"github.com/jspback/bingus/internal/scan"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -59,19 +61,22 @@ func (m UIPingModel) Init() tea.Cmd {
 
 func startScan(timeout time.Duration, maxHosts int) tea.Cmd {
 	return func() tea.Msg {
-		hostFoundCh := make(chan string, 100)
-
-		go func() {
-			for host := range hostFoundCh {
-				program.Send(hostFoundMsg(host))
+		// program.Send blocks until the model has taken the message, so
+		// the scan slows down rather than lose hosts when the UI lags.
+		var summary scan.Summary
+		handle := func(event scan.Event) {
+			switch event := event.(type) {
+			case scan.HostEvent:
+				program.Send(hostFoundMsg(event.Host.IP))
+			case scan.SummaryEvent:
+				summary = event.Summary
 			}
-		}()
-
-		hosts, err := hostDiscovery(timeout, hostFoundCh, maxHosts)
+		}
 
-		close(hostFoundCh)
+		opts := scan.HostOptions{Timeout: timeout, MaxHosts: maxHosts}
+		_, err := scan.HostDiscovery(context.Background(), opts, handle)
 
-		return scanDoneMsg{Hosts: hosts, Err: err}
+		return scanDoneMsg{Summary: summary, Err: err}
 	}
 }
 
@@ -163,6 +168,7 @@ func (m UIPingModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case scanDoneMsg:
 		m.scanning = false
 		m.state = StateResults
+		m.summary = msg.Summary
 		if msg.Err != nil {
 			m.error = msg.Err
 		}
@@ -305,6 +311,11 @@ func (m UIPingModel) View() string {
 			resultsContent.WriteString(m.styles.WarningStyle.Render("No hosts found on the network.\n"))
 		}
 
+		if summary := m.summary; !summary.End.IsZero() {
+			resultsContent.WriteString(m.styles.SectionStyle.Render(fmt.Sprintf("\nPinged %d addresses in %v",
+				summary.Probes, summary.End.Sub(summary.Start).Round(time.Millisecond))))
+		}
+
 		sb.WriteString(resultsBox.Render(resultsContent.String()))
 		sb.WriteString("\n\n")
 		sb.WriteString(m.styles.HelpStyle.Render("Press Enter to return to main menu"))
@@ -1,105 +1,32 @@
 package port
 
 import (
-	"context"
-	"fmt"
-	"net"
-	"sync"
 	"time"
+
+	"github.com/jspback/bingus/internal/services"
+	"github.com/jspback/bingus/internal/util"
 )
 
-func scanPort(ctx context.Context, host string, port int, timeout time.Duration) PortResult {
-	dialer := net.Dialer{Timeout: timeout}
+// Service inspection needs full TLS, HTTP or SSH exchanges, so it gets a
+// multiple of the connect timeout configured for the scan.
+const inspectTimeoutFactor = 4
 
-	address := fmt.Sprintf("%s:%d", host, port)
+// minProbeTimeout is the lowest RTT-derived probe timeout, as for the CLI.
+const minProbeTimeout = 100 * time.Millisecond
 
-	conn, err := dialer.DialContext(ctx, "tcp", address)
+// portsToScan expands the chosen port set, or the start/end range when the
+// "range" entry is selected.
+func portsToScan(portSet string, startPort, endPort int) []util.Port {
+	numbers, err := services.Set(portSet)
 	if err != nil {
-		return PortResult{Port: port, Open: false, Error: err}
-	}
-
-	defer conn.Close()
-
-	return PortResult{Port: port, Open: true, Error: nil}
-}
-
-func portDiscovery(ctx context.Context, hosts []string, portsToScan []int, timeout time.Duration, portFoundCh chan PortResult) (map[string][]int, error) {
-	results := make(map[string][]int)
-
-	var hostWg sync.WaitGroup
-
-	resultsMutex := sync.Mutex{}
-
-	hostSem := make(chan struct{}, len(hosts))
-
-	for _, host := range hosts {
-		hostWg.Add(1)
-
-		select {
-		case hostSem <- struct{}{}:
-		case <-ctx.Done():
-			hostWg.Done()
-			continue
+		for port := startPort; port <= endPort; port++ {
+			numbers = append(numbers, port)
 		}
-
-		go func(host string) {
-			defer hostWg.Done()
-			defer func() { <-hostSem }()
-
-			var portWg sync.WaitGroup
-
-			portSem := make(chan struct{}, 100)
-
-			openPorts := make([]int, 0)
-			openPortsMutex := sync.Mutex{}
-
-			for _, port := range portsToScan {
-				select {
-				case <-ctx.Done():
-					return
-				default:
-				}
-
-				portWg.Add(1)
-
-				select {
-				case portSem <- struct{}{}:
-				case <-ctx.Done():
-					portWg.Done()
-					continue
-				}
-
-				go func(port int) {
-					defer portWg.Done()
-					defer func() { <-portSem }()
-
-					result := scanPort(ctx, host, port, timeout)
-
-					select {
-					case portFoundCh <- result:
-					default:
-					}
-
-					if result.Open {
-						openPortsMutex.Lock()
-						openPorts = append(openPorts, port)
-						openPortsMutex.Unlock()
-					}
-				}(port)
-			}
-
-			portWg.Wait()
-			close(portSem)
-
-			resultsMutex.Lock()
-			results[host] = openPorts
-			resultsMutex.Unlock()
-
-		}(host)
 	}
 
-	hostWg.Wait()
-	close(hostSem)
-
-	return results, nil
+	ports := make([]util.Port, 0, len(numbers))
+	for _, number := range numbers {
+		ports = append(ports, util.Port{Number: number, Proto: "tcp"})
+	}
+	return ports
 }
@@ -4,17 +4,12 @@ import (
 	"context"
 
 	"github.com/jspback/bingus/bta/internal/ui"
+	"github.com/jspback/bingus/internal/scan"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
 )
 
-type PortResult struct {
-	Port  int
-	Open  bool
-	Error error
-}
-
 type PortState int
 
 type HostItem struct {
@@ -23,34 +18,36 @@ type HostItem struct {
 }
 
 type portFoundMsg struct {
-	Host string
-	Port int
-	Open bool
+	Result scan.PortResult
 }
 
 type scanDoneMsg struct {
-	Results map[string][]int
+	Results map[string][]scan.PortResult
+	Summary scan.Summary
 	Err     error
 }
 
 type UIPortModel struct {
-	state          PortState
-	hosts          []HostItem
-	cursor         int
-	selectAll      bool
-	inputs         []textinput.Model
-	focusIndex     int
-	spinner        spinner.Model
-	scanResults    map[string][]int
-	currentHost    string
-	currentPort    int
-	scanProgress   map[string]int
-	quitting       bool
-	scanning       bool
-	error          error
-	styles         *ui.Styles
-	width          int
-	height         int
-	cancel         context.CancelFunc
-	useCommonPorts bool
+	state        PortState
+	hosts        []HostItem
+	cursor       int
+	selectAll    bool
+	inputs       []textinput.Model
+	focusIndex   int
+	spinner      spinner.Model
+	scanResults  map[string][]scan.PortResult
+	summary      scan.Summary
+	currentHost  string
+	currentPort  int
+	scanProgress map[string]int
+	quitting     bool
+	scanning     bool
+	error        error
+	styles       *ui.Styles
+	width        int
+	height       int
+	cancel       context.CancelFunc
+	portSet      int
+	timing       int
+	inspect      bool
 }
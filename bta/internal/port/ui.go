@@ -3,12 +3,16 @@ package port
 import (
 	"context"
 	"fmt"
+	"net"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	"github.com/jspback/bingus/bta/internal/ui"
-	"github.com/jspback/bingus/bta/internal/utils"
+	"github.com/jspback/bingus/internal/scan"
+	"github.com/jspback/bingus/internal/services"
+	"github.com/jspback/bingus/internal/util"
 
 	"github.com/charmbracelet/bubbles/spinner"
 	"github.com/charmbracelet/bubbles/textinput"
@@ -16,6 +20,17 @@ import (
 	"github.com/charmbracelet/lipgloss"
 )
 
+// portSets are the choices offered by the port set selector. The first entry
+// scans the start/end range typed into the inputs; the rest are named sets.
+var portSets = append([]string{"range"}, services.SetNames...)
+
+// timings are the choices offered by the timing selector. Presets ignore the
+// timeout input, which only applies to the custom profile.
+var timings = scan.TimingNames()
+
+// defaultTiming is the selector's initial choice.
+var defaultTiming = slices.Index(timings, "normal")
+
 const (
 	StateHostSelection PortState = iota
 	StatePortConfig
@@ -52,17 +67,18 @@ func NewUIPortModel() UIPortModel {
 	s.Style = styles.SuccessStyle
 
 	return UIPortModel{
-		state:          StateHostSelection,
-		hosts:          []HostItem{},
-		inputs:         inputs,
-		focusIndex:     0,
-		spinner:        s,
-		scanResults:    make(map[string][]int),
-		scanProgress:   make(map[string]int),
-		styles:         styles,
-		width:          80,
-		height:         24,
-		useCommonPorts: false,
+		state:        StateHostSelection,
+		hosts:        []HostItem{},
+		inputs:       inputs,
+		focusIndex:   0,
+		spinner:      s,
+		scanResults:  make(map[string][]scan.PortResult),
+		scanProgress: make(map[string]int),
+		styles:       styles,
+		width:        80,
+		height:       24,
+		portSet:      0,
+		timing:       defaultTiming,
 	}
 }
 
@@ -77,69 +93,37 @@ func (m *UIPortModel) SetHosts(hosts []string) {
 	}
 }
 
-func startScan(ctx context.Context, hosts []string, startPort, endPort int, timeout time.Duration, useCommonPorts bool) tea.Cmd {
+func startScan(ctx context.Context, hosts []string, ports []util.Port, timing scan.Timing, inspect bool) tea.Cmd {
 	return func() tea.Msg {
-		portFoundCh := make(chan PortResult, 100)
-
-		hostPortMap := make(map[string]map[int]bool)
-		for _, host := range hosts {
-			hostPortMap[host] = make(map[int]bool)
-		}
-
-		portsToScan := []int{}
-		if useCommonPorts {
-			portsToScan = utils.GetCommonPorts()
-		} else {
-			for port := startPort; port <= endPort; port++ {
-				portsToScan = append(portsToScan, port)
+		// program.Send blocks until the model has taken the message, so
+		// the scan slows down rather than lose results when the UI lags.
+		var summary scan.Summary
+		handle := func(event scan.Event) {
+			switch event := event.(type) {
+			case scan.PortEvent:
+				program.Send(portFoundMsg{Result: event.Result})
+			case scan.SummaryEvent:
+				summary = event.Summary
 			}
 		}
 
-		for _, host := range hosts {
-			go func(host string) {
-				for _, port := range portsToScan {
-					hostPortMap[host][port] = false
-				}
-			}(host)
+		opts := scan.PortOptions{
+			Timeout:        timing.Timeout,
+			MinTimeout:     min(minProbeTimeout, timing.Timeout),
+			MaxTimeout:     timing.MaxTimeout,
+			Inspect:        inspect,
+			InspectTimeout: timing.MaxTimeout * inspectTimeoutFactor,
+			Retries:        timing.Retries,
+			RateLimiter:    util.NewRateLimiter(timing.Rate, timing.ScanDelay),
+			MaxConcurrency: timing.PortConcurrency,
 		}
+		targets, err := scan.ResolveTargets(ctx, hosts, "ip", scan.ResolveAll)
+		if err != nil {
+			return scanDoneMsg{Err: err}
+		}
+		results, err := scan.PortDiscovery(ctx, targets, ports, opts, handle)
 
-		go func() {
-			currentHost := ""
-			currentPort := 0
-
-			for _, host := range hosts {
-				for _, port := range portsToScan {
-					currentHost = host
-					currentPort = port
-
-					program.Send(portFoundMsg{
-						Host: currentHost,
-						Port: currentPort,
-						Open: false,
-					})
-				}
-			}
-
-			for result := range portFoundCh {
-				if result.Open {
-					for host, ports := range hostPortMap {
-						if _, exists := ports[result.Port]; exists {
-							program.Send(portFoundMsg{
-								Host: host,
-								Port: result.Port,
-								Open: true,
-							})
-						}
-					}
-				}
-			}
-		}()
-
-		results, err := portDiscovery(ctx, hosts, portsToScan, timeout, portFoundCh)
-
-		close(portFoundCh)
-
-		return scanDoneMsg{Results: results, Err: err}
+		return scanDoneMsg{Results: results, Summary: summary, Err: err}
 	}
 }
 
@@ -182,7 +166,9 @@ func (m UIPortModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					m.cursor = len(m.hosts)
 				}
 			} else if m.state == StatePortConfig {
-				if m.focusIndex == len(m.inputs) {
+				if m.focusIndex > len(m.inputs) {
+					m.focusIndex--
+				} else if m.focusIndex == len(m.inputs) {
 					m.inputs[len(m.inputs)-1].Focus()
 					m.focusIndex = len(m.inputs) - 1
 				} else if m.focusIndex > 0 {
@@ -218,6 +204,8 @@ func (m UIPortModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 				} else if m.focusIndex == len(m.inputs)-1 {
 					m.inputs[m.focusIndex].Blur()
 					m.focusIndex = len(m.inputs)
+				} else if m.focusIndex < len(m.inputs)+2 {
+					m.focusIndex++
 				}
 			}
 			return m, nil
@@ -233,16 +221,38 @@ func (m UIPortModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					}
 				}
 			} else if m.state == StatePortConfig && m.focusIndex == len(m.inputs) {
-				m.useCommonPorts = !m.useCommonPorts
+				m.portSet = (m.portSet + 1) % len(portSets)
+			} else if m.state == StatePortConfig && m.focusIndex == len(m.inputs)+1 {
+				m.timing = (m.timing + 1) % len(timings)
+			} else if m.state == StatePortConfig && m.focusIndex == len(m.inputs)+2 {
+				m.inspect = !m.inspect
 			}
 			return m, nil
 
+		case "left", "right":
+			if m.state == StatePortConfig && m.focusIndex == len(m.inputs) {
+				if msg.String() == "right" {
+					m.portSet = (m.portSet + 1) % len(portSets)
+				} else {
+					m.portSet = (m.portSet - 1 + len(portSets)) % len(portSets)
+				}
+				return m, nil
+			}
+			if m.state == StatePortConfig && m.focusIndex == len(m.inputs)+1 {
+				if msg.String() == "right" {
+					m.timing = (m.timing + 1) % len(timings)
+				} else {
+					m.timing = (m.timing - 1 + len(timings)) % len(timings)
+				}
+				return m, nil
+			}
+
 		case "tab", "shift+tab":
 			if m.state == StatePortConfig {
 				if msg.String() == "tab" {
-					m.focusIndex = (m.focusIndex + 1) % (len(m.inputs) + 1)
+					m.focusIndex = (m.focusIndex + 1) % (len(m.inputs) + 3)
 				} else {
-					m.focusIndex = (m.focusIndex - 1 + len(m.inputs) + 1) % (len(m.inputs) + 1)
+					m.focusIndex = (m.focusIndex - 1 + len(m.inputs) + 3) % (len(m.inputs) + 3)
 				}
 
 				for i := range m.inputs {
@@ -297,6 +307,12 @@ func (m UIPortModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 					startPort, endPort = endPort, startPort
 				}
 
+				timing, _ := scan.LookupTiming(timings[m.timing])
+				if timing.Name == scan.CustomTiming {
+					timing.Timeout = time.Duration(timeout) * time.Millisecond
+					timing.MaxTimeout = timing.Timeout
+				}
+
 				selectedHosts := make([]string, 0, len(m.hosts))
 				for _, h := range m.hosts {
 					if h.Selected {
@@ -306,15 +322,16 @@ func (m UIPortModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 				m.state = StateScanning
 				m.scanning = true
-				m.scanResults = make(map[string][]int)
+				m.scanResults = make(map[string][]scan.PortResult)
 				m.scanProgress = make(map[string]int)
+				m.currentHost = ""
 
 				ctx, cancel := context.WithCancel(context.Background())
 				m.cancel = cancel
 
 				return m, tea.Batch(
 					m.spinner.Tick,
-					startScan(ctx, selectedHosts, startPort, endPort, time.Duration(timeout)*time.Millisecond, m.useCommonPorts),
+					startScan(ctx, selectedHosts, portsToScan(portSets[m.portSet], startPort, endPort), timing, m.inspect),
 				)
 
 			} else if m.state == StateResults {
@@ -331,13 +348,14 @@ func (m UIPortModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 
 	case portFoundMsg:
-
-		if msg.Open {
-			m.currentHost = fmt.Sprintf("%s:%d", msg.Host, msg.Port)
-			m.currentPort = msg.Port
+		result := msg.Result
+		if result.Open {
+			m.currentHost = net.JoinHostPort(result.Host, strconv.Itoa(result.Port))
+			m.currentPort = result.Port
+			m.scanResults[result.Host] = append(m.scanResults[result.Host], result)
 		}
 
-		m.scanProgress[msg.Host]++
+		m.scanProgress[result.Host]++
 
 		if m.state == StateScanning {
 			return m, m.spinner.Tick
@@ -350,6 +368,7 @@ func (m UIPortModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.error = msg.Err
 		}
 		m.scanResults = msg.Results
+		m.summary = msg.Summary
 
 		if m.cancel != nil {
 			m.cancel()
@@ -480,27 +499,67 @@ func (m UIPortModel) View() string {
 
 		inputsContent.WriteString("\n\n")
 
-		commonPortsCheckbox := "[ ]"
-		if m.useCommonPorts {
-			commonPortsCheckbox = "[x]"
+		var setChoices []string
+		for i, name := range portSets {
+			if name == "range" {
+				name = "port range"
+			}
+			if i == m.portSet {
+				name = "[" + name + "]"
+			}
+			setChoices = append(setChoices, name)
 		}
 
-		checkboxStyle := m.styles.ItemStyle
 		if m.focusIndex == len(m.inputs) {
-			checkboxStyle = m.styles.SelectedItemStyle
-			inputsContent.WriteString(checkboxStyle.Render(fmt.Sprintf("> %s Use Common Ports (overrides port range)", commonPortsCheckbox)))
+			inputsContent.WriteString(m.styles.SelectedItemStyle.Render("> Ports: " + strings.Join(setChoices, " ")))
+		} else {
+			inputsContent.WriteString(m.styles.ItemStyle.Render("  Ports: " + strings.Join(setChoices, " ")))
+		}
+
+		inputsContent.WriteString("\n")
+
+		var timingChoices []string
+		for i, name := range timings {
+			if i == m.timing {
+				name = "[" + name + "]"
+			}
+			timingChoices = append(timingChoices, name)
+		}
+
+		if m.focusIndex == len(m.inputs)+1 {
+			inputsContent.WriteString(m.styles.SelectedItemStyle.Render("> Timing: " + strings.Join(timingChoices, " ")))
+		} else {
+			inputsContent.WriteString(m.styles.ItemStyle.Render("  Timing: " + strings.Join(timingChoices, " ")))
+		}
+
+		inputsContent.WriteString("\n")
+
+		inspectCheckbox := "[ ]"
+		if m.inspect {
+			inspectCheckbox = "[x]"
+		}
+
+		if m.focusIndex == len(m.inputs)+2 {
+			inputsContent.WriteString(m.styles.SelectedItemStyle.Render(fmt.Sprintf("> %s Inspect services (TLS, HTTP, SSH)", inspectCheckbox)))
 		} else {
-			inputsContent.WriteString(checkboxStyle.Render(fmt.Sprintf("  %s Use Common Ports (overrides port range)", commonPortsCheckbox)))
+			inputsContent.WriteString(m.styles.ItemStyle.Render(fmt.Sprintf("  %s Inspect services (TLS, HTTP, SSH)", inspectCheckbox)))
 		}
 
-		if m.useCommonPorts {
+		if set := portSets[m.portSet]; set != "range" {
+			ports, _ := services.Set(set)
 			inputsContent.WriteString("\n\n")
-			inputsContent.WriteString(m.styles.SectionStyle.Render("Common ports include: 21, 22, 23, 25, 53, 80, 443, 3306, 3389, 8080, etc."))
+			inputsContent.WriteString(m.styles.SectionStyle.Render(fmt.Sprintf("Scanning %d ports from the %s set (overrides port range)", len(ports), set)))
+		}
+
+		if timing, _ := scan.LookupTiming(timings[m.timing]); timing.Name != scan.CustomTiming {
+			inputsContent.WriteString("\n\n")
+			inputsContent.WriteString(m.styles.SectionStyle.Render(fmt.Sprintf("%s timing: %v timeout (up to %v), %d probes in flight, %d retries (overrides timeout)",
+				timing.Name, timing.Timeout, timing.MaxTimeout, timing.PortConcurrency, timing.Retries)))
 		}
 
 		sb.WriteString(contentBox.Render(inputsContent.String()))
 		sb.WriteString("\n\n")
-		sb.WriteString(m.styles.HelpStyle.Render("Tab: Switch fields • Space: Toggle checkbox • Enter: Start scan • Esc: Back"))
+		sb.WriteString(m.styles.HelpStyle.Render("Tab: Switch fields • ←/→: Choose port set or timing • Space: Toggle • Enter: Start scan • Esc: Back"))
 
 	case StateScanning:
 		sb.WriteString(boxStyle.Render(m.styles.SectionStyle.Render("Port Scanning in Progress")))
@@ -509,8 +568,8 @@ func (m UIPortModel) View() string {
 		var scanContent strings.Builder
 		scanContent.WriteString(fmt.Sprintf("%s Scanning ports...\n\n", m.spinner.View()))
 
-		if m.useCommonPorts {
-			scanContent.WriteString(m.styles.SuccessStyle.Render("Scanning common ports only\n\n"))
+		if set := portSets[m.portSet]; set != "range" {
+			scanContent.WriteString(m.styles.SuccessStyle.Render(fmt.Sprintf("Scanning the %s port set\n\n", set)))
 		}
 
 		if m.currentHost != "" {
@@ -546,17 +605,12 @@ func (m UIPortModel) View() string {
 				resultsContent.WriteString(m.styles.SuccessStyle.Render(fmt.Sprintf("  %d open ports:\n", openPortCount)))
 
 				var portsStr strings.Builder
-				for i, port := range ports {
-					portsStr.WriteString(fmt.Sprintf("  %5d", port))
-					if (i+1)%5 == 0 {
-						portsStr.WriteString("\n")
-					}
-				}
-				if openPortCount%5 != 0 {
-					portsStr.WriteString("\n")
+				for _, port := range ports {
+					portsStr.WriteString(fmt.Sprintf("  %-10s %-14s %s\n", fmt.Sprintf("%d/%s", port.Port, port.Proto), port.Service, inspectSummary(port)))
 				}
 
 				resultsContent.WriteString(portsStr.String())
+				resultsContent.WriteString(m.renderHTTPColumns(ports))
 			} else {
 				resultsContent.WriteString(m.styles.WarningStyle.Render("  No open ports found\n"))
 			}
@@ -566,6 +620,10 @@ func (m UIPortModel) View() string {
 
 		resultsContent.WriteString(m.styles.SectionStyle.Render(fmt.Sprintf("Total: %d open ports found across %d hosts",
 			totalOpenPorts, len(m.scanResults))))
+		if summary := m.summary; !summary.End.IsZero() {
+			resultsContent.WriteString(m.styles.SectionStyle.Render(fmt.Sprintf("\nProbed %d ports in %v",
+				summary.Probes, summary.End.Sub(summary.Start).Round(time.Millisecond))))
+		}
 
 		sb.WriteString(contentBox.Render(resultsContent.String()))
 		sb.WriteString("\n\n")
@@ -574,3 +632,67 @@ func (m UIPortModel) View() string {
 
 	return sb.String()
 }
+
+func (m UIPortModel) renderHTTPColumns(ports []scan.PortResult) string {
+	var sb strings.Builder
+
+	for _, port := range ports {
+		if port.HTTP == nil && port.HTTPError == nil {
+			continue
+		}
+
+		if sb.Len() == 0 {
+			sb.WriteString(fmt.Sprintf("\n  %-6s %-6s %-18s %-14s %-28s %-8s %-12s\n",
+				"PORT", "STATUS", "SERVER", "POWERED-BY", "TITLE", "LENGTH", "FAVICON"))
+		}
+
+		if port.HTTPError != nil {
+			sb.WriteString(fmt.Sprintf("  %-6d %s\n", port.Port,
+				m.styles.WarningStyle.Render(fmt.Sprintf("fingerprint failed: %v", port.HTTPError))))
+			continue
+		}
+
+		info := port.HTTP
+		sb.WriteString(fmt.Sprintf("  %-6d %-6d %-18s %-14s %-28s %-8d %-12s\n",
+			port.Port, info.StatusCode, truncate(info.Server, 18), truncate(info.PoweredBy, 14),
+			truncate(info.Title, 28), info.ContentLength, truncate(info.FaviconHash, 12)))
+
+		for _, location := range info.Redirects {
+			sb.WriteString(fmt.Sprintf("         -> %s\n", location))
+		}
+	}
+
+	return sb.String()
+}
+
+// inspectSummary condenses the TLS and SSH findings for a port into one line;
+// HTTP findings get their own columns.
+func inspectSummary(port scan.PortResult) string {
+	var parts []string
+	if tls := port.TLS; tls != nil {
+		switch {
+		case tls.Error != "":
+			parts = append(parts, "TLS failed: "+tls.Error)
+		case tls.StartTLS && !tls.Offered:
+			parts = append(parts, "no STARTTLS")
+		case tls.Certificate != nil:
+			parts = append(parts, fmt.Sprintf("%s (%s)", tls.Version, tls.Certificate.Subject))
+		default:
+			parts = append(parts, tls.Version)
+		}
+	}
+	if ssh := port.SSH; ssh != nil {
+		parts = append(parts, ssh.Banner)
+	}
+	return strings.Join(parts, " • ")
+}
+
+func truncate(s string, width int) string {
+	if s == "" {
+		return "-"
+	}
+	if runes := []rune(s); len(runes) > width {
+		return string(runes[:width-1]) + "…"
+	}
+	return s
+}
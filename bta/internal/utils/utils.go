@@ -1,10 +0,0 @@
-package utils
-
-func GetCommonPorts() []int {
-	return []int{
-		20, 21, 22, 23, 25, 53, 67, 68, 80, 110,
-		123, 143, 161, 443, 445, 465, 500, 587,
-		631, 993, 995, 1433, 1521, 3306, 3389,
-		5432, 5900, 8080, 8443,
-	}
-}
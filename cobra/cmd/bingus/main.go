@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"os"
 
@@ -9,7 +10,9 @@ import (
 
 func main() {
 	rootCmd := cmd.NewRootCmd()
-	if err := rootCmd.Execute(); err != nil {
+	if err := rootCmd.Execute(); errors.Is(err, cmd.ErrInterrupted) {
+		os.Exit(cmd.ExitInterrupted)
+	} else if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
 	}
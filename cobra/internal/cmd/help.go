@@ -17,6 +17,7 @@ func NewHelpCmd() *cobra.Command {
 COMMANDS:
   ping        Scan for hosts on your network using ICMP echo requests
   port        Scan for open ports on specified hosts using TCP connections
+  resume      Continue an interrupted port scan from its checkpoint file
   help        Display this help information
 
 EXAMPLES:
@@ -29,9 +30,72 @@ EXAMPLES:
   # Scan a range of ports
   bingus port --hosts 192.168.1.1 --ports 1-1000
 
+  # Scan by service name or named port set (common, top1-top100, all)
+  bingus port --hosts 192.168.1.1 --ports ssh,https,mysql
+  bingus port --hosts 192.168.1.1 --ports top20
+
+  # Scan TCP and UDP ports together, excluding the NetBIOS range
+  bingus port --hosts 192.168.1.1 --ports T:1-1024,!135-139,U:53,161
+
+  # Stay under 100 probes per second on a production network
+  bingus port --hosts 10.0.0.0/24 --ports top100 --rate 100
+
+  # Scan gently, or as fast as the network allows (polite, normal, aggressive, insane)
+  bingus port --hosts 10.0.0.0/24 --ports top100 -T polite
+  bingus port --hosts 192.168.1.1 --ports all -T insane --retries 1
+
+  # Retry ports that time out on a lossy network
+  bingus port --hosts 192.168.1.1 --ports top100 --retries 2
+
+  # Interleave probes across a subnet in a reproducible random order
+  bingus port --hosts 10.0.0.0/24 --ports top100 --randomize --seed 1234
+
+  # Test a firewall rule that only allows one source address and port
+  bingus port --hosts 10.0.0.5 --ports 22,443 --source-ip 10.0.0.2 --source-port 53
+
+  # Audit a network that is only reachable through a bastion's SOCKS tunnel
+  bingus port --hosts 10.20.0.0/24 --ports top100 --proxy socks5://127.0.0.1:1080
+
+  # Scan every address a name resolves to, or only the first
+  bingus port --hosts db01.example.com --ports 22,5432
+  bingus port --hosts www.example.com --ports 443 --resolve first
+
+  # Scan IPv6 targets: literals, bracketed literals or small prefixes
+  bingus port --hosts 2001:db8::10,[2001:db8::11] --ports 22,443
+  bingus port --hosts 2001:db8::/120 --ports top100
+  bingus port --hosts dualstack.example.com --ports 443 -6
+
+  # Keep an overnight audit resumable, then continue it after an interruption
+  bingus port --hosts 10.0.0.0/24 --ports all --checkpoint audit.json
+  bingus resume audit.json
+
+  # Hide the live progress line (it is already off when stderr is redirected)
+  bingus port --hosts 10.0.0.0/24 --ports top100 --no-progress
+
+  # Write one JSON document with metadata, hosts, ports and statistics
+  bingus port --hosts 10.0.0.0/24 --ports top100 --output json > scan.json
+  bingus ping --output json
+
+  # Stream host, port, progress and summary events as JSON Lines
+  bingus port --hosts 10.0.0.0/24 --ports top100 --output jsonl | jq 'select(.state == "open")'
+
+  # Export open ports to CSV for a spreadsheet while watching the usual output,
+  # or every port with its state
+  bingus port --hosts 10.0.0.0/24 --ports top100 --output csv --output-file assets.csv
+  bingus port --hosts 192.168.1.1 --ports 1-1024 --output csv --all-states > ports.csv
+
+  # Feed report generators and vulnerability management that import nmap XML
+  bingus port --hosts 10.0.0.0/24 --ports top100 --output nmap-xml --output-file scan.xml
+
+  # One line per host for grep and awk
+  bingus port --hosts 10.0.0.0/24 --ports top100 --output grep | grep '/open/tcp//ssh'
+
   # Scan multiple hosts
   bingus port --hosts 192.168.1.1,192.168.1.2 --ports 22,80,443
 
+  # Inspect TLS, STARTTLS, HTTP and SSH services
+  bingus port --hosts 192.168.1.10 --ports 22,25,80,143,443,587,8080 --inspect
+
 For more details on each command, use:
   bingus [command] --help`)
 		},
@@ -0,0 +1,193 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jspback/bingus/internal/report"
+	"github.com/jspback/bingus/internal/scan"
+)
+
+// version is the bingus version reported by --version and in reports. Release
+// builds set it with -ldflags "-X github.com/jspback/bingus/cobra/internal/cmd.version=v1.2.3".
+var version = ""
+
+// bingusVersion falls back to the module version of `go install` builds.
+func bingusVersion() string {
+	if version != "" {
+		return version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
+// outputFormats are the values of --output; text is the human-readable
+// default and the others are machine-readable.
+var outputFormats = []string{"text", "json", "jsonl", "csv", "nmap-xml", "grep"}
+
+// progressEventInterval is how often --output jsonl reports progress.
+const progressEventInterval = time.Second
+
+var outputUsage = "Output format: " + strings.Join(outputFormats, ", ")
+
+const outputFileUsage = "Write the --output format to this file instead of stdout, keeping the human-readable output on stdout"
+
+// output routes a command's results. With a machine-readable format on
+// stdout, the human-readable text is dropped so stdout holds only the
+// document, and notices the user still needs go to stderr. With an output
+// file, the document goes there and stdout is left as it is.
+type output struct {
+	format string
+	file   *os.File
+	// text receives the human-readable results and progress messages.
+	text io.Writer
+	// notice receives messages that matter even when text is dropped.
+	notice io.Writer
+	data   io.Writer
+
+	// stream and the counts below serve --output jsonl.
+	stream        *report.Stream
+	mu            sync.Mutex
+	start         time.Time
+	total         int
+	completed     int
+	done, open    int
+	stop, stopped chan struct{}
+}
+
+// newOutput checks the --output and --output-file flags. The file, if any, is
+// created right away so a bad path fails before the scan; close it when done.
+func newOutput(format, path string, verbose bool) (*output, error) {
+	if !slices.Contains(outputFormats, format) {
+		return nil, fmt.Errorf("unknown output format %q (want %s)", format, strings.Join(outputFormats, ", "))
+	}
+
+	o := &output{format: format, text: os.Stdout, notice: os.Stdout, data: os.Stdout}
+	if path != "" {
+		if !o.machine() {
+			return nil, fmt.Errorf("--output-file needs a machine-readable --output format (%s)", strings.Join(outputFormats[1:], ", "))
+		}
+		file, err := os.Create(path)
+		if err != nil {
+			return nil, fmt.Errorf("error creating output file: %w", err)
+		}
+		o.file, o.data = file, file
+		return o, nil
+	}
+
+	if o.machine() {
+		// Verbose logging writes to stdout and would corrupt the document.
+		if verbose {
+			return nil, fmt.Errorf("--verbose cannot be combined with --output %s", format)
+		}
+		o.text, o.notice = io.Discard, os.Stderr
+	}
+	return o, nil
+}
+
+func (o *output) machine() bool {
+	return o.format != "text"
+}
+
+// close closes the output file, if any; closing again does nothing.
+func (o *output) close() error {
+	if o.file == nil {
+		return nil
+	}
+	file := o.file
+	o.file = nil
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("error writing output file: %w", err)
+	}
+	return nil
+}
+
+// begin starts streaming a scan of total probes, completed of which were
+// done by an earlier run. Host discovery passes 0, as it does not know its
+// total up front or see the hosts that stay silent, and gets no progress
+// events.
+func (o *output) begin(total, completed int) {
+	if o.format != "jsonl" {
+		return
+	}
+
+	o.stream = report.NewStream(o.data)
+	o.start, o.total, o.completed, o.done = time.Now(), total, completed, completed
+	o.stop, o.stopped = make(chan struct{}), make(chan struct{})
+	if total == 0 {
+		close(o.stopped)
+		return
+	}
+
+	go func() {
+		defer close(o.stopped)
+		ticker := time.NewTicker(progressEventInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-o.stop:
+				return
+			case <-ticker.C:
+				o.mu.Lock()
+				done, open := o.done, o.open
+				o.mu.Unlock()
+				elapsed := time.Since(o.start)
+				rate := float64(done-o.completed) / elapsed.Seconds()
+				o.stream.Progress(done, o.total, open, elapsed, rate)
+			}
+		}
+	}()
+}
+
+// event streams one of the scan's events.
+func (o *output) event(event scan.Event) {
+	if o.stream == nil {
+		return
+	}
+
+	switch event := event.(type) {
+	case scan.HostEvent:
+		o.count(true)
+		o.stream.Host(event.Host)
+	case scan.PortEvent:
+		o.count(event.Result.Open)
+		o.stream.Port(event.Result)
+	}
+}
+
+func (o *output) count(open bool) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.done++
+	if open {
+		o.open++
+	}
+}
+
+// report writes the finished scan's document in the machine-readable format.
+func (o *output) report(r *report.Report) error {
+	switch o.format {
+	case "json":
+		return r.WriteJSON(o.data)
+	case "jsonl":
+		close(o.stop)
+		<-o.stopped
+		return o.stream.Summary(r)
+	case "csv":
+		return r.WriteCSV(o.data)
+	case "nmap-xml":
+		return r.WriteNmapXML(o.data)
+	case "grep":
+		return r.WriteGrep(o.data)
+	}
+	return nil
+}
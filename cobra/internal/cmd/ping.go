@@ -3,10 +3,12 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"os"
 	"time"
 
-	"github.com/jspback/bingus/cobra/internal/ping"
-	"github.com/jspback/bingus/cobra/internal/util"
+	"github.com/jspback/bingus/internal/report"
+	"github.com/jspback/bingus/internal/scan"
+	"github.com/jspback/bingus/internal/util"
 	"github.com/spf13/cobra"
 )
 
@@ -14,41 +16,122 @@ func NewPingCmd() *cobra.Command {
 	var timeout time.Duration
 	var maxHosts int
 	var verbose bool
+	var retries int
+	var rate float64
+	var scanDelay time.Duration
+	var concurrency int
+	var timing string
+	var sourceIP string
+	var outputFormat string
+	var outputFile string
 
 	pingCmd := &cobra.Command{
 		Use:   "ping",
 		Short: "Scan for hosts on your network",
 		Long:  `Scan for hosts on your network using ICMP echo requests`,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			fmt.Println("Scanning for hosts on the network...")
+			out, err := newOutput(outputFormat, outputFile, verbose)
+			if err != nil {
+				return err
+			}
+			defer out.close()
+
+			if err := applyTiming(cmd, timing, map[string]func(scan.Timing){
+				"timeout":     func(t scan.Timing) { timeout = t.Timeout },
+				"concurrency": func(t scan.Timing) { concurrency = t.HostConcurrency },
+				"rate":        func(t scan.Timing) { rate = t.Rate },
+				"scan-delay":  func(t scan.Timing) { scanDelay = t.ScanDelay },
+				"retries":     func(t scan.Timing) { retries = t.Retries },
+			}); err != nil {
+				return err
+			}
+
+			if rate < 0 || scanDelay < 0 {
+				return fmt.Errorf("--rate and --scan-delay must not be negative")
+			}
+			if retries < 0 {
+				return fmt.Errorf("--retries must not be negative")
+			}
+			if concurrency < 0 {
+				return fmt.Errorf("--concurrency must not be negative")
+			}
+
+			source, err := parseSource(sourceIP, 0)
+			if err != nil {
+				return err
+			}
+			if source.IP != nil && source.IP.To4() == nil {
+				return fmt.Errorf("--source-ip must be an IPv4 address for host discovery")
+			}
+
+			fmt.Fprintln(out.text, "Scanning for hosts on the network...")
 
 			ctx := context.WithValue(context.Background(), "verbose", verbose)
 			logger := util.NewVerboseLogger(ctx)
 
+			ctx, stop := interruptContext(ctx)
+			defer stop()
+
+			logger.Print("Using %s timing\n", timing)
 			logger.Print("Using timeout of %v per host\n", timeout)
 			logger.Print("Maximum hosts to scan: %d\n", maxHosts)
 			logger.Print("Starting scan at %v\n", time.Now().Format(time.RFC3339))
 
-			hostFoundCh := make(chan string)
-			go func() {
-				for host := range hostFoundCh {
-					fmt.Printf("Host found: %s\n", host)
+			var summary scan.Summary
+			handle := func(event scan.Event) {
+				switch event := event.(type) {
+				case scan.HostEvent:
+					out.event(event)
+					fmt.Fprintf(out.text, "Host found: %s\n", event.Host.IP)
+				case scan.SummaryEvent:
+					summary = event.Summary
 				}
-			}()
+			}
 
-			hosts, err := ping.HostDiscovery(ctx, timeout, hostFoundCh, maxHosts)
-			if err != nil {
+			opts := scan.HostOptions{
+				Timeout:        timeout,
+				MaxHosts:       maxHosts,
+				Retries:        retries,
+				RateLimiter:    util.NewRateLimiter(rate, scanDelay),
+				MaxConcurrency: concurrency,
+				Source:         source,
+			}
+
+			out.begin(0, 0)
+			hosts, err := scan.HostDiscovery(ctx, opts, handle)
+			partial := err != nil && ctx.Err() != nil
+			if err != nil && !partial {
 				return fmt.Errorf("error during host discovery: %w", err)
 			}
 
-			close(hostFoundCh)
 			logger.Print("Scan completed at %v\n", time.Now().Format(time.RFC3339))
 
-			fmt.Printf("\nScan complete. Found %d hosts on the network.\n", len(hosts))
+			if partial {
+				fmt.Fprintf(out.text, "\nScan interrupted. Found %d hosts before stopping (partial results).\n", len(hosts))
+			} else {
+				fmt.Fprintf(out.text, "\nScan complete. Found %d hosts on the network.\n", len(hosts))
+			}
 			for i, host := range hosts {
-				fmt.Printf("%d. %s\n", i+1, host)
+				if host.Attempts > 1 {
+					fmt.Fprintf(out.text, "%d. %s (answered on attempt %d)\n", i+1, host.IP, host.Attempts)
+				} else {
+					fmt.Fprintf(out.text, "%d. %s\n", i+1, host.IP)
+				}
+			}
+
+			printStats(out.text, summary, verbose)
+
+			meta := report.Meta{Version: bingusVersion(), Command: "ping", Args: os.Args[1:]}
+			if err := out.report(report.Hosts(meta, hosts, summary)); err != nil {
+				return err
+			}
+			if err := out.close(); err != nil {
+				return err
 			}
 
+			if partial {
+				return interrupted(cmd)
+			}
 			return nil
 		},
 	}
@@ -56,6 +139,14 @@ func NewPingCmd() *cobra.Command {
 	pingCmd.Flags().DurationVarP(&timeout, "timeout", "t", 500*time.Millisecond, "Timeout for each host ping (default: 500ms)")
 	pingCmd.Flags().IntVarP(&maxHosts, "max-hosts", "m", 50, "Maximum number of hosts to scan (default: 50)")
 	pingCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	pingCmd.Flags().IntVar(&retries, "retries", 0, "Ping hosts that did not answer again up to this many times")
+	pingCmd.Flags().Float64Var(&rate, "rate", 0, "Maximum pings per second (0 for unlimited)")
+	pingCmd.Flags().DurationVar(&scanDelay, "scan-delay", 0, "Minimum delay between pings")
+	pingCmd.Flags().IntVar(&concurrency, "concurrency", 0, "Maximum pings in flight (0 for the default of 256)")
+	pingCmd.Flags().StringVarP(&timing, "timing", "T", "normal", timingUsage)
+	pingCmd.Flags().StringVar(&sourceIP, "source-ip", "", "Local address to ping from; also selects the subnet to scan")
+	pingCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", outputUsage)
+	pingCmd.Flags().StringVar(&outputFile, "output-file", "", outputFileUsage)
 
 	return pingCmd
 }
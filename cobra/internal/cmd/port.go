@@ -3,19 +3,56 @@ package cmd
 import (
 	"context"
 	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"slices"
+	"sort"
 	"strings"
+	"text/tabwriter"
 	"time"
 
-	"github.com/jspback/bingus/cobra/internal/port"
-	"github.com/jspback/bingus/cobra/internal/util"
+	"github.com/jspback/bingus/internal/probe"
+	"github.com/jspback/bingus/internal/report"
+	"github.com/jspback/bingus/internal/scan"
+	"github.com/jspback/bingus/internal/services"
+	"github.com/jspback/bingus/internal/util"
 	"github.com/spf13/cobra"
 )
 
 func NewPortCmd() *cobra.Command {
+	return newPortCmd(nil)
+}
+
+// newPortCmd builds the port command. With a checkpoint it continues that
+// scan: the checkpoint's targets, ports and seed replace those the flags
+// would produce, and finished probes are skipped.
+func newPortCmd(resume *scan.Checkpoint) *cobra.Command {
 	var timeout time.Duration
 	var hostsFlag []string
 	var portsFlag string
 	var verbose bool
+	var inspect bool
+	var inspectTimeout time.Duration
+	var minTimeout time.Duration
+	var maxTimeout time.Duration
+	var retries int
+	var randomize bool
+	var seed int64
+	var rate float64
+	var scanDelay time.Duration
+	var concurrency int
+	var timing string
+	var sourceIP string
+	var sourcePort int
+	var proxyFlag string
+	var resolve string
+	var ipv4, ipv6 bool
+	var checkpointPath string
+	var noProgress bool
+	var outputFormat string
+	var outputFile string
+	var allStates bool
 
 	portCmd := &cobra.Command{
 		Use:   "port",
@@ -26,6 +63,15 @@ func NewPortCmd() *cobra.Command {
 				return fmt.Errorf("at least one host must be specified")
 			}
 
+			out, err := newOutput(outputFormat, outputFile, verbose)
+			if err != nil {
+				return err
+			}
+			defer out.close()
+			if allStates && outputFormat != "csv" {
+				return fmt.Errorf("--all-states only applies to --output csv")
+			}
+
 			ctx := context.WithValue(context.Background(), "verbose", verbose)
 			logger := util.NewVerboseLogger(ctx)
 
@@ -44,77 +90,374 @@ func NewPortCmd() *cobra.Command {
 				}
 			}
 
-			portsToScan, err := util.ParsePortRange(portsFlag, logger)
+			if err := applyTiming(cmd, timing, map[string]func(scan.Timing){
+				"timeout":     func(t scan.Timing) { timeout = t.Timeout },
+				"max-timeout": func(t scan.Timing) { maxTimeout = t.MaxTimeout },
+				"concurrency": func(t scan.Timing) { concurrency = t.PortConcurrency },
+				"rate":        func(t scan.Timing) { rate = t.Rate },
+				"scan-delay":  func(t scan.Timing) { scanDelay = t.ScanDelay },
+				"retries":     func(t scan.Timing) { retries = t.Retries },
+			}); err != nil {
+				return err
+			}
+
+			if rate < 0 || scanDelay < 0 {
+				return fmt.Errorf("--rate and --scan-delay must not be negative")
+			}
+			if retries < 0 {
+				return fmt.Errorf("--retries must not be negative")
+			}
+			if concurrency < 0 {
+				return fmt.Errorf("--concurrency must not be negative")
+			}
+			if maxTimeout > 0 && minTimeout > maxTimeout {
+				return fmt.Errorf("--min-timeout %v is larger than --max-timeout %v", minTimeout, maxTimeout)
+			}
+
+			network := "ip"
+			switch {
+			case ipv4 && ipv6:
+				return fmt.Errorf("-4 and -6 cannot be used together")
+			case ipv4:
+				network = "ip4"
+			case ipv6:
+				network = "ip6"
+			}
+
+			source, err := parseSource(sourceIP, sourcePort)
 			if err != nil {
 				return err
 			}
 
-			fmt.Printf("Scanning %d ports on %d hosts (%d total port scans)...\n",
-				len(portsToScan), len(hosts), len(hosts)*len(portsToScan))
+			var proxyURL *url.URL
+			if proxyFlag != "" {
+				if proxyURL, err = scan.ParseProxy(proxyFlag); err != nil {
+					return err
+				}
+				logger.Print("Connecting through %s proxy %s\n", proxyURL.Scheme, proxyURL.Host)
+			}
+
+			// Resolving names can hang on a slow DNS server, so Ctrl-C
+			// covers it as well as the scan.
+			ctx, stop := interruptContext(ctx)
+			defer stop()
+
+			var targets []scan.Target
+			var portsToScan []util.Port
+			if resume != nil {
+				targets, portsToScan, seed = resume.Targets, resume.Ports, resume.Seed
+				fmt.Fprintf(out.notice, "Resuming scan from %s: %d of %d port scans already done\n",
+					resume.Path(), resume.Completed(), len(targets)*len(portsToScan))
+			} else {
+				// SOCKS5h and HTTP CONNECT proxies resolve hostnames
+				// themselves, which reaches names only the proxy's network
+				// knows.
+				if proxyURL != nil && proxyURL.Scheme != "socks5" {
+					targets = scan.UnresolvedTargets(hosts)
+				} else if targets, err = scan.ResolveTargets(ctx, hosts, network, resolve); err != nil {
+					if ctx.Err() != nil {
+						return interrupted(cmd)
+					}
+					return err
+				}
+
+				if portsToScan, err = util.ParsePortSpec(portsFlag, logger); err != nil {
+					return err
+				}
 
-			ctx, cancel := context.WithCancel(ctx)
-			defer cancel()
+				fmt.Fprintf(out.text, "Scanning %d ports on %d hosts (%d total port scans)...\n",
+					len(portsToScan), len(targets), len(targets)*len(portsToScan))
+			}
 
-			bufferSize := 100
-			if len(hosts)*len(portsToScan) < bufferSize {
-				bufferSize = len(hosts) * len(portsToScan)
+			total, completed := len(targets)*len(portsToScan), 0
+			if resume != nil {
+				completed = resume.Completed()
 			}
-			portFoundCh := make(chan port.PortResult, bufferSize)
+			// Verbose logging writes from the scan's goroutines, which
+			// would tear through the progress line.
+			bar := newProgress(total, completed, !noProgress && !verbose)
+			out.begin(total, completed)
 
-			done := make(chan struct{})
-			go func() {
-				defer close(done)
-				for result := range portFoundCh {
-					if result.Open {
-						fmt.Printf("Found open port %d on host %s\n", result.Port, result.Host)
-					} else if verbose {
-						fmt.Printf("Port %d on host %s is closed: %v\n", result.Port, result.Host, result.Error)
+			// Closed and filtered results are only kept for --all-states;
+			// a resumed scan has lost those of the earlier run.
+			closed := make(map[string][]scan.PortResult)
+			var summary scan.Summary
+			handle := func(event scan.Event) {
+				switch event := event.(type) {
+				case scan.PortEvent:
+					if allStates && !event.Result.Open {
+						closed[event.Result.Host] = append(closed[event.Result.Host], event.Result)
 					}
+					out.event(event)
+					bar.add(event.Result.Open)
+					bar.print(func() { printPortResult(out.text, event.Result, verbose) })
+				case scan.SummaryEvent:
+					summary = event.Summary
 				}
-			}()
+			}
 
 			if timeout == 0 {
 				timeout = 500 * time.Millisecond
 			}
 
-			logger.Print("Using timeout of %v per connection\n", timeout)
+			logger.Print("Using %s timing\n", timing)
+			logger.Print("Using initial timeout of %v per connection, adapting between %v and %v\n", timeout, minTimeout, maxTimeout)
+			if randomize {
+				if !cmd.Flags().Changed("seed") && resume == nil {
+					seed = time.Now().UnixNano()
+				}
+				fmt.Fprintf(out.text, "Randomizing probe order (--seed %d)\n", seed)
+			}
+
+			checkpoint := resume
+			if checkpoint == nil && checkpointPath != "" {
+				checkpoint = scan.NewCheckpoint(checkpointPath, changedFlags(cmd), seed, targets, portsToScan)
+			}
+
 			logger.Print("Starting scan at %v\n", time.Now().Format(time.RFC3339))
 
-			results, err := port.PortDiscovery(ctx, hosts, portsToScan, timeout, portFoundCh)
-			if err != nil {
-				return fmt.Errorf("error during port discovery: %w", err)
+			opts := scan.PortOptions{
+				Timeout:        timeout,
+				MinTimeout:     minTimeout,
+				MaxTimeout:     maxTimeout,
+				Inspect:        inspect,
+				InspectTimeout: inspectTimeout,
+				Retries:        retries,
+				Randomize:      randomize,
+				Seed:           seed,
+				RateLimiter:    util.NewRateLimiter(rate, scanDelay),
+				MaxConcurrency: concurrency,
+				Source:         source,
+				Proxy:          proxyURL,
+				Checkpoint:     checkpoint,
 			}
 
-			close(portFoundCh)
-			<-done
+			results, err := scan.PortDiscovery(ctx, targets, portsToScan, opts, handle)
+			bar.close()
+			partial := err != nil && ctx.Err() != nil
+			if err != nil && checkpoint != nil {
+				fmt.Fprintf(out.notice, "Progress saved; continue with: bingus resume %s\n", checkpoint.Path())
+			}
+			if err != nil && !partial {
+				return fmt.Errorf("error during port discovery: %w", err)
+			}
 
-			logger.Print("Scan completed at %v\n", time.Now().Format(time.RFC3339))
+			logger.Print("Scan completed at %v: %d probes in %v\n",
+				summary.End.Format(time.RFC3339), summary.Probes, summary.End.Sub(summary.Start).Round(time.Millisecond))
 
-			fmt.Println("\nScan complete. Found open ports:")
+			if partial {
+				fmt.Fprintf(out.text, "\nScan interrupted after %d of %d port scans. Partial results:\n", summary.Probes, len(targets)*len(portsToScan))
+			} else {
+				fmt.Fprintln(out.text, "\nScan complete. Found open ports:")
+			}
 			openHostCount := 0
-			for host, openPorts := range results {
+			var httpResults []scan.PortResult
+			for _, target := range targets {
+				openPorts := results[target.Address]
 				if len(openPorts) > 0 {
 					openHostCount++
-					fmt.Printf("%s: %v\n", host, openPorts)
+					sort.Slice(openPorts, func(i, j int) bool {
+						if openPorts[i].Proto != openPorts[j].Proto {
+							return openPorts[i].Proto == "tcp"
+						}
+						return openPorts[i].Port < openPorts[j].Port
+					})
+					portNames := make([]string, 0, len(openPorts))
+					for _, result := range openPorts {
+						portNames = append(portNames, services.Format(result.Port, result.Proto))
+						if result.HTTP != nil {
+							httpResults = append(httpResults, result)
+						}
+					}
+					fmt.Fprintf(out.text, "%s: %s\n", target, strings.Join(portNames, ", "))
 				} else if verbose {
-					fmt.Printf("%s: No open ports found\n", host)
+					fmt.Fprintf(out.text, "%s: No open ports found\n", target)
 				}
 			}
 
 			if openHostCount == 0 {
-				fmt.Println("No open ports found on any hosts")
+				fmt.Fprintln(out.text, "No open ports found on any hosts")
+			}
+
+			if len(httpResults) > 0 {
+				fmt.Fprintln(out.text, "\nHTTP services:")
+				printHTTPTable(out.text, httpResults)
+			}
+
+			printStats(out.text, summary, verbose)
+
+			meta := report.Meta{Version: bingusVersion(), Command: "port", Args: redactArgs(os.Args[1:]), Ports: portsToScan}
+			reported := results
+			if allStates {
+				reported = make(map[string][]scan.PortResult, len(results))
+				for _, target := range targets {
+					reported[target.Address] = append(slices.Clone(results[target.Address]), closed[target.Address]...)
+				}
+			}
+			if err := out.report(report.Ports(meta, targets, reported, summary)); err != nil {
+				return err
+			}
+			if err := out.close(); err != nil {
+				return err
 			}
 
+			if partial {
+				return interrupted(cmd)
+			}
 			return nil
 		},
 	}
 
-	portCmd.Flags().DurationVarP(&timeout, "timeout", "t", 500*time.Millisecond, "Timeout for each port scan")
-	portCmd.Flags().StringSliceVarP(&hostsFlag, "hosts", "H", []string{}, "Hosts to scan (comma-separated, CIDR notation supported, e.g., 192.168.1.0/24)")
-	portCmd.Flags().StringVarP(&portsFlag, "ports", "p", "21,22,23,25,53,80,110,139,143,443,445,993,995,3306,3389,5900,8080", "Ports to scan (comma-separated, ranges allowed e.g. 80-100)")
+	portCmd.Flags().DurationVarP(&timeout, "timeout", "t", 500*time.Millisecond, "Initial timeout for each port probe, until the host's RTT is measured")
+	portCmd.Flags().DurationVar(&minTimeout, "min-timeout", 100*time.Millisecond, "Lower bound for RTT-derived probe timeouts")
+	portCmd.Flags().DurationVar(&maxTimeout, "max-timeout", 5*time.Second, "Upper bound for RTT-derived probe timeouts")
+	portCmd.Flags().StringSliceVarP(&hostsFlag, "hosts", "H", []string{}, "Hosts to scan (comma-separated names or IPv4/IPv6 addresses, link-local with a zone such as fe80::1%eth0, CIDR notation supported, e.g., 192.168.1.0/24 or 2001:db8::/120)")
+	portCmd.Flags().StringVarP(&portsFlag, "ports", "p", "common", "Ports to scan: numbers, ranges (80-100), service names (ssh,https) or sets (common, top1-top100, all); T:/U: select TCP or UDP, ! excludes")
 	portCmd.Flags().BoolVarP(&verbose, "verbose", "v", false, "Enable verbose output")
+	portCmd.Flags().BoolVar(&inspect, "inspect", false, "Inspect open ports for TLS, STARTTLS, HTTP and SSH services")
+	portCmd.Flags().DurationVar(&inspectTimeout, "inspect-timeout", 5*time.Second, "Timeout for inspecting each open port")
+
+	portCmd.Flags().IntVar(&retries, "retries", 0, "Probe ports that timed out again up to this many times")
+	portCmd.Flags().BoolVar(&randomize, "randomize", false, "Probe the host×port space in a random order, interleaving hosts")
+	portCmd.Flags().Int64Var(&seed, "seed", 0, "Seed for --randomize, to repeat a previous order (random by default)")
+	portCmd.Flags().Float64Var(&rate, "rate", 0, "Maximum probes per second across the whole scan (0 for unlimited)")
+	portCmd.Flags().DurationVar(&scanDelay, "scan-delay", 0, "Minimum delay between probes across the whole scan")
+	portCmd.Flags().IntVar(&concurrency, "concurrency", 0, "Maximum probes in flight (0 for the default of 1024)")
+	portCmd.Flags().StringVarP(&timing, "timing", "T", "normal", timingUsage)
+	portCmd.Flags().StringVar(&sourceIP, "source-ip", "", "Local address to send probes from")
+	portCmd.Flags().IntVar(&sourcePort, "source-port", 0, "Local port to send probes from (0 for any)")
+	portCmd.Flags().StringVar(&resolve, "resolve", scan.ResolveAll, "Which addresses of a hostname to scan: first or all")
+	portCmd.Flags().BoolVarP(&ipv4, "ipv4", "4", false, "Scan only IPv4 addresses")
+	portCmd.Flags().BoolVarP(&ipv6, "ipv6", "6", false, "Scan only IPv6 addresses")
+	portCmd.Flags().StringVar(&checkpointPath, "checkpoint", "", "Save progress to this file every few seconds, so `bingus resume` can continue an interrupted scan")
+	portCmd.Flags().StringVarP(&outputFormat, "output", "o", "text", outputUsage)
+	portCmd.Flags().StringVar(&outputFile, "output-file", "", outputFileUsage)
+	portCmd.Flags().BoolVar(&allStates, "all-states", false, "With --output csv, also write rows for closed and filtered ports, not only open ones")
+	portCmd.Flags().BoolVar(&noProgress, "no-progress", false, "Do not show the progress line on stderr (it is only shown when stderr is a terminal)")
+	portCmd.Flags().StringVar(&proxyFlag, "proxy", "", "Send TCP connect probes through a proxy: socks5://[user:pass@]host:1080 or http://host:3128")
 
 	portCmd.MarkFlagRequired("hosts")
 
 	return portCmd
 }
+
+func printPortResult(w io.Writer, result scan.PortResult, verbose bool) {
+	if !result.Open {
+		if verbose {
+			fmt.Fprintf(w, "Port %s on host %s is %s after %d attempt(s): %v\n",
+				services.Format(result.Port, result.Proto), resultTarget(result), result.State, result.Attempts, result.Error)
+		}
+		return
+	}
+
+	fmt.Fprintf(w, "Found open port %s on host %s\n", services.Format(result.Port, result.Proto), resultTarget(result))
+	if result.TLS != nil {
+		printTLSInfo(w, result.TLS)
+	}
+	if result.HTTP != nil {
+		printHTTPInfo(w, result.HTTP)
+	}
+	if result.SSH != nil {
+		printSSHInfo(w, result.SSH)
+	}
+}
+
+func resultTarget(result scan.PortResult) scan.Target {
+	return scan.Target{Hostname: result.Hostname, Address: result.Host}
+}
+
+func printTLSInfo(w io.Writer, info *probe.TLSInfo) {
+	if info.Error != "" {
+		if info.StartTLS && info.Offered {
+			fmt.Fprintf(w, "  TLS: STARTTLS offered (%s) but %s\n", info.Protocol, info.Error)
+		} else {
+			fmt.Fprintf(w, "  TLS: %s\n", info.Error)
+		}
+		return
+	}
+
+	if info.StartTLS && !info.Offered {
+		fmt.Fprintf(w, "  TLS: STARTTLS not offered (%s)\n", info.Protocol)
+		return
+	}
+
+	if info.StartTLS {
+		fmt.Fprintf(w, "  TLS: STARTTLS (%s) %s, %s\n", info.Protocol, info.Version, info.CipherSuite)
+	} else {
+		fmt.Fprintf(w, "  TLS: %s, %s\n", info.Version, info.CipherSuite)
+	}
+
+	if cert := info.Certificate; cert != nil {
+		fmt.Fprintf(w, "  Certificate: %s\n", cert.Subject)
+		fmt.Fprintf(w, "    Issuer: %s\n", cert.Issuer)
+		if len(cert.DNSNames) > 0 {
+			fmt.Fprintf(w, "    DNS names: %s\n", strings.Join(cert.DNSNames, ", "))
+		}
+		fmt.Fprintf(w, "    Valid: %s to %s\n", cert.NotBefore.Format(time.RFC3339), cert.NotAfter.Format(time.RFC3339))
+		fmt.Fprintf(w, "    SHA256: %s\n", cert.SHA256)
+	}
+}
+
+func printHTTPInfo(w io.Writer, info *probe.HTTPInfo) {
+	fmt.Fprintf(w, "  HTTP: %d %s\n", info.StatusCode, info.URL)
+	if info.Title != "" {
+		fmt.Fprintf(w, "    Title: %s\n", info.Title)
+	}
+	if info.Server != "" {
+		fmt.Fprintf(w, "    Server: %s\n", info.Server)
+	}
+	if info.PoweredBy != "" {
+		fmt.Fprintf(w, "    X-Powered-By: %s\n", info.PoweredBy)
+	}
+	for _, location := range info.Redirects {
+		fmt.Fprintf(w, "    Redirect: %s\n", location)
+	}
+	fmt.Fprintf(w, "    Content length: %d\n", info.ContentLength)
+	if info.FaviconHash != "" {
+		fmt.Fprintf(w, "    Favicon hash: %s\n", info.FaviconHash)
+	}
+}
+
+func printSSHInfo(w io.Writer, info *probe.SSHInfo) {
+	fmt.Fprintf(w, "  SSH: %s\n", info.Banner)
+	fmt.Fprintf(w, "    Key exchange: %s\n", strings.Join(info.KexAlgorithms, ", "))
+	fmt.Fprintf(w, "    Host key algorithms: %s\n", strings.Join(info.HostKeyAlgorithms, ", "))
+	fmt.Fprintf(w, "    Ciphers: %s\n", strings.Join(info.Ciphers, ", "))
+	fmt.Fprintf(w, "    MACs: %s\n", strings.Join(info.MACs, ", "))
+	for _, key := range info.HostKeys {
+		fmt.Fprintf(w, "    Host key: %s %s\n", key.Type, key.Fingerprint)
+	}
+	if info.HostKeyError != "" {
+		fmt.Fprintf(w, "    Host keys: unavailable (%s)\n", info.HostKeyError)
+	}
+	if len(info.Weak) > 0 {
+		fmt.Fprintf(w, "    Weak algorithms: %s\n", strings.Join(info.Weak, ", "))
+	}
+}
+
+func printHTTPTable(out io.Writer, results []scan.PortResult) {
+	sort.Slice(results, func(i, j int) bool {
+		if results[i].Host != results[j].Host {
+			return results[i].Host < results[j].Host
+		}
+		return results[i].Port < results[j].Port
+	})
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "HOST\tPORT\tSTATUS\tSERVER\tPOWERED-BY\tTITLE\tLENGTH\tFAVICON\tREDIRECTS")
+	for _, result := range results {
+		info := result.HTTP
+		fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			resultTarget(result), result.Port, info.StatusCode,
+			orDash(info.Server), orDash(info.PoweredBy), orDash(info.Title),
+			info.ContentLength, orDash(info.FaviconHash), orDash(strings.Join(info.Redirects, " -> ")))
+	}
+	w.Flush()
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
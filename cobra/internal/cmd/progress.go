@@ -0,0 +1,163 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	progressInterval = 250 * time.Millisecond
+	progressBarWidth = 30
+	// rateSmoothing weighs the latest interval in the smoothed probe rate.
+	rateSmoothing = 0.3
+)
+
+// progress draws a one-line progress display on stderr while a scan runs.
+// Findings printed through print clear the line first, so they do not mix
+// with it. A nil *progress draws nothing.
+type progress struct {
+	mu    sync.Mutex
+	out   io.Writer
+	total int
+	done  int
+	open  int
+	shown bool
+
+	lastDone int
+	lastTime time.Time
+	rate     float64
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newProgress starts a progress display for total probes of which done are
+// already finished, unless it is disabled or stderr is not a terminal.
+func newProgress(total, done int, enabled bool) *progress {
+	if !enabled || !isTerminal(os.Stderr) {
+		return nil
+	}
+
+	p := &progress{
+		out:      os.Stderr,
+		total:    total,
+		done:     done,
+		lastDone: done,
+		lastTime: time.Now(),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	go func() {
+		defer close(p.stopped)
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-p.stop:
+				return
+			case now := <-ticker.C:
+				p.mu.Lock()
+				p.sample(now)
+				p.draw()
+				p.mu.Unlock()
+			}
+		}
+	}()
+
+	return p
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// add counts one finished probe.
+func (p *progress) add(open bool) {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.done++
+	if open {
+		p.open++
+	}
+}
+
+// print runs fn, which writes to stdout, with the progress line cleared.
+func (p *progress) print(fn func()) {
+	if p == nil {
+		fn()
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.clear()
+	fn()
+	p.draw()
+}
+
+// close stops the display and removes its line.
+func (p *progress) close() {
+	if p == nil {
+		return
+	}
+	close(p.stop)
+	<-p.stopped
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.clear()
+}
+
+func (p *progress) sample(now time.Time) {
+	if elapsed := now.Sub(p.lastTime).Seconds(); elapsed > 0 {
+		current := float64(p.done-p.lastDone) / elapsed
+		if p.rate == 0 {
+			p.rate = current
+		} else {
+			p.rate = rateSmoothing*current + (1-rateSmoothing)*p.rate
+		}
+	}
+	p.lastDone, p.lastTime = p.done, now
+}
+
+func (p *progress) draw() {
+	fmt.Fprintf(p.out, "\r%s\x1b[K", progressLine(p.done, p.total, p.open, p.rate))
+	p.shown = true
+}
+
+// progressLine renders the progress display for done of total probes at rate
+// probes per second.
+func progressLine(done, total, open int, rate float64) string {
+	fraction := 1.0
+	if total > 0 {
+		fraction = float64(done) / float64(total)
+	}
+	filled := int(fraction * progressBarWidth)
+
+	eta := "--"
+	if rate > 0 {
+		remaining := time.Duration(float64(total-done) / rate * float64(time.Second))
+		eta = remaining.Round(time.Second).String()
+	}
+
+	return fmt.Sprintf("[%s%s] %d/%d (%.1f%%)  %.0f probes/s  %d open  ETA %s",
+		strings.Repeat("#", filled), strings.Repeat(".", progressBarWidth-filled),
+		done, total, fraction*100, rate, open, eta)
+}
+
+func (p *progress) clear() {
+	if p.shown {
+		fmt.Fprint(p.out, "\r\x1b[K")
+		p.shown = false
+	}
+}
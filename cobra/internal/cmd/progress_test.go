@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressLine(t *testing.T) {
+	tests := []struct {
+		done, total, open int
+		rate              float64
+		want              string
+	}{
+		{0, 100, 0, 0, "[..............................] 0/100 (0.0%)  0 probes/s  0 open  ETA --"},
+		{50, 100, 2, 10, "[###############...............] 50/100 (50.0%)  10 probes/s  2 open  ETA 5s"},
+		{7200, 10000, 1, 1, "[#####################.........] 7200/10000 (72.0%)  1 probes/s  1 open  ETA 46m40s"},
+		{100, 100, 3, 40, "[##############################] 100/100 (100.0%)  40 probes/s  3 open  ETA 0s"},
+		{0, 0, 0, 0, "[##############################] 0/0 (100.0%)  0 probes/s  0 open  ETA --"},
+	}
+
+	for _, tt := range tests {
+		if got := progressLine(tt.done, tt.total, tt.open, tt.rate); got != tt.want {
+			t.Errorf("progressLine(%d, %d, %d, %v) = %q, want %q", tt.done, tt.total, tt.open, tt.rate, got, tt.want)
+		}
+	}
+}
+
+func TestProgressSample(t *testing.T) {
+	start := time.Now()
+	p := &progress{lastTime: start}
+
+	p.done = 100
+	p.sample(start.Add(time.Second))
+	if p.rate != 100 {
+		t.Fatalf("first rate = %v, want 100", p.rate)
+	}
+
+	// A stall only pulls the smoothed rate down part of the way.
+	p.sample(start.Add(2 * time.Second))
+	if p.rate != 70 {
+		t.Fatalf("rate after a stalled second = %v, want 70", p.rate)
+	}
+}
+
+func TestProgressPrintClearsLine(t *testing.T) {
+	var out strings.Builder
+	p := &progress{out: &out, total: 10}
+	p.draw()
+	out.Reset()
+
+	printed := false
+	p.print(func() { printed = true })
+	if !printed {
+		t.Fatal("print did not run its function")
+	}
+	if !strings.HasPrefix(out.String(), "\r\x1b[K") {
+		t.Errorf("print did not clear the progress line first: %q", out.String())
+	}
+
+	var nilProgress *progress
+	nilProgress.add(true)
+	nilProgress.print(func() {})
+	nilProgress.close()
+}
@@ -10,6 +10,7 @@ func NewRootCmd() *cobra.Command {
 		Short: "Bingus - A network scanning tool",
 		Long: `Bingus is a command-line network scanning tool 
 that allows you to discover hosts on your network and scan ports.`,
+		Version: bingusVersion(),
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) == 0 {
 				cmd.Help()
@@ -19,6 +20,7 @@ that allows you to discover hosts on your network and scan ports.`,
 
 	rootCmd.AddCommand(NewPingCmd())
 	rootCmd.AddCommand(NewPortCmd())
+	rootCmd.AddCommand(NewResumeCmd())
 	rootCmd.AddCommand(NewHelpCmd())
 
 	return rootCmd
@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+)
+
+// ExitInterrupted is the exit status of a scan stopped by SIGINT or SIGTERM,
+// whether it printed partial results or was forced to exit.
+const ExitInterrupted = 130
+
+// ErrInterrupted is returned by commands whose scan was interrupted after
+// printing partial results.
+var ErrInterrupted = errors.New("scan interrupted")
+
+// interruptContext returns a context that is cancelled by the first SIGINT
+// or SIGTERM, so the scan drains its in-flight probes and returns what it
+// found. A second signal exits immediately. stop releases the signal handler.
+func interruptContext(parent context.Context) (ctx context.Context, stop func()) {
+	ctx, cancel := context.WithCancel(parent)
+
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	done := make(chan struct{})
+
+	go func() {
+		select {
+		case <-signals:
+		case <-done:
+			return
+		}
+		fmt.Fprintln(os.Stderr, "\nInterrupted, waiting for in-flight probes (press Ctrl-C again to exit immediately)")
+		cancel()
+
+		select {
+		case <-signals:
+			fmt.Fprintln(os.Stderr, "Exiting without results")
+			os.Exit(ExitInterrupted)
+		case <-done:
+		}
+	}()
+
+	return ctx, func() {
+		signal.Stop(signals)
+		close(done)
+		cancel()
+	}
+}
+
+// interrupted reports the end of an interrupted scan: the partial results are
+// already printed, so only the exit status is left to set.
+func interrupted(cmd *cobra.Command) error {
+	cmd.SilenceErrors = true
+	cmd.SilenceUsage = true
+	return ErrInterrupted
+}
@@ -0,0 +1,30 @@
+package cmd
+
+import (
+	"fmt"
+	"net"
+
+	"github.com/jspback/bingus/internal/scan"
+)
+
+// parseSource checks --source-ip and --source-port before the scan starts,
+// so a typo or an address of another machine is reported once instead of as
+// a failure of every probe.
+func parseSource(sourceIP string, sourcePort int) (scan.Source, error) {
+	var source scan.Source
+	if sourceIP != "" {
+		source.IP = net.ParseIP(sourceIP)
+		if source.IP == nil {
+			return source, fmt.Errorf("invalid --source-ip %q", sourceIP)
+		}
+	}
+	if sourcePort < 0 || sourcePort > 65535 {
+		return source, fmt.Errorf("--source-port must be between 0 and 65535")
+	}
+	source.Port = sourcePort
+
+	if err := source.Validate(); err != nil {
+		return source, err
+	}
+	return source, nil
+}
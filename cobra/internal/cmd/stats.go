@@ -0,0 +1,101 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jspback/bingus/internal/scan"
+)
+
+// slowestHosts is how many per-host durations the statistics show without
+// --verbose.
+const slowestHosts = 10
+
+// stateOrder lists port and host states in the order the statistics show
+// them; states not listed follow alphabetically.
+var stateOrder = []string{"open", "closed", "filtered", "open|filtered", "up", "down"}
+
+func printStats(out io.Writer, summary scan.Summary, verbose bool) {
+	stats := summary.Stats
+	elapsed := summary.End.Sub(summary.Start)
+
+	fmt.Fprintln(out, "\nStatistics:")
+	fmt.Fprintf(out, "  Probes: %d in %v (%.1f/s)\n", summary.Probes, elapsed.Round(time.Millisecond), summary.Rate())
+	if len(stats.States) > 0 {
+		fmt.Fprintf(out, "  States: %s\n", formatCounts(stats.States, stateRank))
+	}
+	if len(stats.Errors) > 0 {
+		fmt.Fprintf(out, "  Errors: %s\n", formatCounts(stats.Errors, nil))
+	}
+	if rtt := stats.RTT; rtt.Samples > 0 {
+		fmt.Fprintf(out, "  RTT: min %v, p50 %v, p90 %v, p99 %v, max %v (%d samples)\n",
+			roundRTT(rtt.Min), roundRTT(rtt.P50), roundRTT(rtt.P90), roundRTT(rtt.P99), roundRTT(rtt.Max), rtt.Samples)
+	}
+
+	if len(stats.Hosts) == 0 {
+		return
+	}
+	hosts := stats.Hosts
+	if !verbose && len(hosts) > slowestHosts {
+		hosts = slices.Clone(hosts)
+		slices.SortStableFunc(hosts, func(a, b scan.HostStats) int { return cmp.Compare(b.Duration, a.Duration) })
+		hosts = hosts[:slowestHosts]
+		fmt.Fprintf(out, "  Slowest %d of %d hosts (--verbose lists all):\n", slowestHosts, len(stats.Hosts))
+	} else {
+		fmt.Fprintln(out, "  Hosts:")
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	for _, host := range hosts {
+		target := scan.Target{Hostname: host.Hostname, Address: host.Address}
+		fmt.Fprintf(w, "    %s\t%v\t%d probes\t%d open\n", target, host.Duration.Round(time.Millisecond), host.Probes, host.Open)
+	}
+	w.Flush()
+}
+
+// formatCounts renders counts as "name count" pairs, ordered by rank when it
+// is given and by descending count otherwise.
+func formatCounts(counts map[string]int, rank func(string) int) string {
+	names := slices.Collect(maps.Keys(counts))
+	slices.SortFunc(names, func(a, b string) int {
+		if rank != nil {
+			if c := cmp.Compare(rank(a), rank(b)); c != 0 {
+				return c
+			}
+		} else if c := cmp.Compare(counts[b], counts[a]); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	})
+
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s %d", name, counts[name])
+	}
+	return strings.Join(parts, ", ")
+}
+
+func stateRank(state string) int {
+	if i := slices.Index(stateOrder, state); i >= 0 {
+		return i
+	}
+	return len(stateOrder)
+}
+
+// roundRTT drops the digits that are only noise at each scale.
+func roundRTT(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(10 * time.Microsecond)
+	default:
+		return d.Round(time.Microsecond)
+	}
+}
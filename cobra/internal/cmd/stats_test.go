@@ -0,0 +1,15 @@
+package cmd
+
+import "testing"
+
+func TestFormatCounts(t *testing.T) {
+	states := map[string]int{"filtered": 5, "open": 1, "closed": 90}
+	if got, want := formatCounts(states, stateRank), "open 1, closed 90, filtered 5"; got != want {
+		t.Errorf("formatCounts(states) = %q, want %q", got, want)
+	}
+
+	errors := map[string]int{"timeout": 5, "refused": 90, "reset": 5}
+	if got, want := formatCounts(errors, nil), "refused 90, reset 5, timeout 5"; got != want {
+		t.Errorf("formatCounts(errors) = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jspback/bingus/internal/scan"
+	"github.com/spf13/cobra"
+)
+
+// timingUsage is the help text shared by the -T flag of ping and port.
+var timingUsage = fmt.Sprintf("Timing template setting timeout, concurrency, rate, retries and scan delay together (%s); explicit flags override it",
+	strings.Join(scan.TimingNames(), ", "))
+
+// applyTiming sets every knob of the named template whose flag was not given
+// on the command line. knobs maps a flag name to the function that copies the
+// template's value into that flag's variable.
+func applyTiming(cmd *cobra.Command, name string, knobs map[string]func(scan.Timing)) error {
+	timing, err := scan.LookupTiming(name)
+	if err != nil {
+		return err
+	}
+	if timing.Name == scan.CustomTiming {
+		return nil
+	}
+
+	for flag, apply := range knobs {
+		if !cmd.Flags().Changed(flag) {
+			apply(timing)
+		}
+	}
+	return nil
+}
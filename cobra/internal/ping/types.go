@@ -1,10 +0,0 @@
-package ping
-
-import (
-	"time"
-)
-
-type PingResult struct {
-	IP  string
-	RTT time.Duration
-}
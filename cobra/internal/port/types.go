@@ -1,8 +0,0 @@
-package port
-
-type PortResult struct {
-	Host  string
-	Port  int
-	Open  bool
-	Error error
-}
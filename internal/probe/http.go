@@ -0,0 +1,269 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/net/html"
+)
+
+const (
+	maxHTTPBodySize  = 1 << 20
+	maxHTTPRedirects = 10
+)
+
+// webPorts maps common web ports to whether they usually serve HTTPS.
+var webPorts = map[int]bool{
+	80:   false,
+	81:   false,
+	443:  true,
+	591:  false,
+	3000: false,
+	5000: false,
+	8000: false,
+	8008: false,
+	8080: false,
+	8081: false,
+	8088: false,
+	8443: true,
+	8888: false,
+	9000: false,
+	9090: false,
+	9443: true,
+}
+
+func SupportsHTTP(port int) bool {
+	_, ok := webPorts[port]
+	return ok
+}
+
+func HTTP(ctx context.Context, host string, port int, timeout time.Duration) (*HTTPInfo, error) {
+	https, ok := webPorts[port]
+	if !ok {
+		return nil, fmt.Errorf("no HTTP probe for port %d", port)
+	}
+
+	schemes := []string{"http", "https"}
+	if https {
+		schemes = []string{"https", "http"}
+	}
+
+	var errs []error
+	for _, scheme := range schemes {
+		info, err := fetchHTTP(ctx, scheme, host, port, timeout)
+		if err == nil {
+			return info, nil
+		}
+		errs = append(errs, fmt.Errorf("%s: %w", scheme, err))
+	}
+
+	return nil, errors.Join(errs...)
+}
+
+func fetchHTTP(ctx context.Context, scheme, host string, port int, timeout time.Duration) (*HTTPInfo, error) {
+	target := &url.URL{Scheme: scheme, Host: net.JoinHostPort(host, strconv.Itoa(port)), Path: "/"}
+
+	var redirects []string
+	client := &http.Client{
+		Timeout: timeout,
+		Transport: &http.Transport{
+			DialContext:       (&net.Dialer{Timeout: timeout}).DialContext,
+			TLSClientConfig:   &tls.Config{InsecureSkipVerify: true},
+			DisableKeepAlives: true,
+		},
+		CheckRedirect: func(req *http.Request, via []*http.Request) error {
+			redirects = append(redirects, req.URL.String())
+			// Only follow redirects that stay on the scanned host.
+			if len(via) >= maxHTTPRedirects || req.URL.Hostname() != host {
+				return http.ErrUseLastResponse
+			}
+			return nil
+		},
+	}
+
+	resp, err := get(ctx, client, target.String())
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBodySize))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &HTTPInfo{
+		URL:           target.String(),
+		StatusCode:    resp.StatusCode,
+		Server:        resp.Header.Get("Server"),
+		PoweredBy:     resp.Header.Get("X-Powered-By"),
+		Redirects:     redirects,
+		ContentLength: resp.ContentLength,
+	}
+	if info.ContentLength < 0 {
+		info.ContentLength = int64(len(body))
+	}
+
+	title, icon := parseHTML(body)
+	info.Title = title
+
+	faviconURL := resp.Request.URL.ResolveReference(&url.URL{Path: "/favicon.ico"})
+	if icon != "" {
+		if ref, err := url.Parse(icon); err == nil {
+			faviconURL = resp.Request.URL.ResolveReference(ref)
+		}
+	}
+	if faviconURL.Hostname() == host {
+		info.FaviconHash = fetchFaviconHash(ctx, client, faviconURL.String())
+	}
+
+	return info, nil
+}
+
+func get(ctx context.Context, client *http.Client, target string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, target, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "bingus")
+	return client.Do(req)
+}
+
+// parseHTML extracts the document title and the first icon link from body.
+func parseHTML(body []byte) (string, string) {
+	var title, icon string
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(body))
+	inTitle := false
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return title, icon
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			token := tokenizer.Token()
+			switch token.Data {
+			case "title":
+				inTitle = title == ""
+			case "link":
+				var rel, href string
+				for _, attr := range token.Attr {
+					switch attr.Key {
+					case "rel":
+						rel = strings.ToLower(attr.Val)
+					case "href":
+						href = attr.Val
+					}
+				}
+				if icon == "" && strings.Contains(rel, "icon") {
+					icon = href
+				}
+			}
+
+		case html.TextToken:
+			if inTitle {
+				title = strings.Join(strings.Fields(string(tokenizer.Text())), " ")
+				inTitle = false
+			}
+
+		case html.EndTagToken:
+			inTitle = false
+		}
+	}
+}
+
+func fetchFaviconHash(ctx context.Context, client *http.Client, target string) string {
+	resp, err := get(ctx, client, target)
+	if err != nil {
+		return ""
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return ""
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxHTTPBodySize))
+	if err != nil || len(data) == 0 {
+		return ""
+	}
+
+	return fmt.Sprint(faviconHash(data))
+}
+
+// faviconHash computes the Shodan-compatible favicon hash: MurmurHash3 of the
+// base64 encoding wrapped at 76 characters with a trailing newline.
+func faviconHash(data []byte) int32 {
+	encoded := base64.StdEncoding.EncodeToString(data)
+
+	var wrapped strings.Builder
+	for len(encoded) > 76 {
+		wrapped.WriteString(encoded[:76])
+		wrapped.WriteByte('\n')
+		encoded = encoded[76:]
+	}
+	wrapped.WriteString(encoded)
+	wrapped.WriteByte('\n')
+
+	return int32(murmur3([]byte(wrapped.String())))
+}
+
+func murmur3(data []byte) uint32 {
+	const (
+		c1 = 0xcc9e2d51
+		c2 = 0x1b873593
+	)
+
+	var h uint32
+	length := len(data)
+
+	for len(data) >= 4 {
+		k := binary.LittleEndian.Uint32(data)
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+
+		h ^= k
+		h = bits.RotateLeft32(h, 13)
+		h = h*5 + 0xe6546b64
+		data = data[4:]
+	}
+
+	var k uint32
+	switch len(data) {
+	case 3:
+		k ^= uint32(data[2]) << 16
+		fallthrough
+	case 2:
+		k ^= uint32(data[1]) << 8
+		fallthrough
+	case 1:
+		k ^= uint32(data[0])
+		k *= c1
+		k = bits.RotateLeft32(k, 15)
+		k *= c2
+		h ^= k
+	}
+
+	h ^= uint32(length)
+	h ^= h >> 16
+	h *= 0x85ebca6b
+	h ^= h >> 13
+	h *= 0xc2b2ae35
+	h ^= h >> 16
+
+	return h
+}
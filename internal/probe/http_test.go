@@ -0,0 +1,112 @@
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMurmur3(t *testing.T) {
+	// Reference values from the mmh3 Python package used by Shodan.
+	tests := map[string]int32{
+		"":      0,
+		"foo":   -156908512,
+		"hello": 613153351,
+	}
+
+	for input, want := range tests {
+		if got := int32(murmur3([]byte(input))); got != want {
+			t.Errorf("murmur3(%q) = %d, want %d", input, got, want)
+		}
+	}
+}
+
+func TestFaviconHashWrapsBase64(t *testing.T) {
+	data := []byte(strings.Repeat("favicon", 20))
+
+	// Output of Python base64.encodebytes, which Shodan hashes.
+	encoded := "ZmF2aWNvbmZhdmljb25mYXZpY29uZmF2aWNvbmZhdmljb25mYXZpY29uZmF2aWNvbmZhdmljb25m\n" +
+		"YXZpY29uZmF2aWNvbmZhdmljb25mYXZpY29uZmF2aWNvbmZhdmljb25mYXZpY29uZmF2aWNvbmZh\n" +
+		"dmljb25mYXZpY29uZmF2aWNvbmZhdmljb24=\n"
+
+	if got, want := faviconHash(data), int32(murmur3([]byte(encoded))); got != want {
+		t.Errorf("faviconHash() = %d, want %d", got, want)
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	body := []byte(`<html><head>
+		<title>
+			Grafana
+		</title>
+		<link rel="stylesheet" href="/style.css">
+		<link rel="shortcut icon" href="/public/img/fav32.png">
+		</head><body><title>ignored</title></body></html>`)
+
+	title, icon := parseHTML(body)
+	if title != "Grafana" {
+		t.Errorf("title = %q, want %q", title, "Grafana")
+	}
+	if icon != "/public/img/fav32.png" {
+		t.Errorf("icon = %q, want %q", icon, "/public/img/fav32.png")
+	}
+}
+
+func TestFetchHTTP(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.Handle("/", http.RedirectHandler("/login", http.StatusFound))
+	mux.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Server", "Jetty(10.0.13)")
+		w.Header().Set("X-Powered-By", "Servlet/4.0")
+		fmt.Fprint(w, "<html><head><title>Sign in [Jenkins]</title></head></html>")
+	})
+	mux.HandleFunc("/favicon.ico", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("icon"))
+	})
+
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	host, portStr, _ := net.SplitHostPort(server.Listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	info, err := fetchHTTP(context.Background(), "http", host, port, 2*time.Second)
+	if err != nil {
+		t.Fatalf("fetchHTTP() error: %v", err)
+	}
+
+	if info.StatusCode != http.StatusOK {
+		t.Errorf("StatusCode = %d, want %d", info.StatusCode, http.StatusOK)
+	}
+	if info.Server != "Jetty(10.0.13)" || info.PoweredBy != "Servlet/4.0" {
+		t.Errorf("Server, PoweredBy = %q, %q", info.Server, info.PoweredBy)
+	}
+	if info.Title != "Sign in [Jenkins]" {
+		t.Errorf("Title = %q", info.Title)
+	}
+	if len(info.Redirects) != 1 || !strings.HasSuffix(info.Redirects[0], "/login") {
+		t.Errorf("Redirects = %v, want the /login hop", info.Redirects)
+	}
+	if want := fmt.Sprint(faviconHash([]byte("icon"))); info.FaviconHash != want {
+		t.Errorf("FaviconHash = %q, want %q", info.FaviconHash, want)
+	}
+}
+
+func TestSupportsHTTP(t *testing.T) {
+	for _, port := range []int{80, 443, 8080, 8443} {
+		if !SupportsHTTP(port) {
+			t.Errorf("SupportsHTTP(%d) = false, want true", port)
+		}
+	}
+	for _, port := range []int{22, 25, 53, 445, 3306, 3389, 5900} {
+		if SupportsHTTP(port) {
+			t.Errorf("SupportsHTTP(%d) = true, want false", port)
+		}
+	}
+}
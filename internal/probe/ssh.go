@@ -0,0 +1,414 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/ecdh"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sshMsgIgnore       = 2
+	sshMsgDebug        = 4
+	sshMsgKexInit      = 20
+	sshMsgKexECDHInit  = 30
+	sshMsgKexECDHReply = 31
+
+	maxSSHPacketSize = 256 * 1024
+	sshBannerTimeout = time.Second
+)
+
+var ErrNotSSH = errors.New("service did not present an SSH banner")
+
+var sshCurves = map[string]ecdh.Curve{
+	"curve25519-sha256":            ecdh.X25519(),
+	"curve25519-sha256@libssh.org": ecdh.X25519(),
+	"ecdh-sha2-nistp256":           ecdh.P256(),
+	"ecdh-sha2-nistp384":           ecdh.P384(),
+	"ecdh-sha2-nistp521":           ecdh.P521(),
+}
+
+type sshSession struct {
+	conn       net.Conn
+	reader     *bufio.Reader
+	banner     string
+	serverInit []byte
+}
+
+// kexInit holds the name-lists of an SSH_MSG_KEXINIT in wire order.
+type kexInit struct {
+	kex         []string
+	hostKey     []string
+	ciphersCS   []string
+	ciphersSC   []string
+	macsCS      []string
+	macsSC      []string
+	compression []string
+	compSC      []string
+}
+
+func SSH(ctx context.Context, host string, port int, timeout time.Duration) (*SSHInfo, error) {
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+
+	session, err := dialSSH(ctx, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	session.conn.Close()
+
+	offer, err := parseKexInit(session.serverInit)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &SSHInfo{
+		Banner:            session.banner,
+		KexAlgorithms:     offer.kex,
+		HostKeyAlgorithms: offer.hostKey,
+		Ciphers:           offer.ciphersSC,
+		MACs:              offer.macsSC,
+		Compression:       offer.compSC,
+	}
+	parseSSHBanner(info)
+
+	kexAlgorithm := selectKex(offer.kex)
+	if kexAlgorithm == "" {
+		info.HostKeyError = "no supported key exchange offered"
+	} else {
+		var failures []string
+		for _, algorithm := range hostKeyProbes(offer.hostKey) {
+			key, err := fetchHostKey(ctx, address, timeout, kexAlgorithm, algorithm, offer)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", algorithm, err))
+				continue
+			}
+			info.HostKeys = append(info.HostKeys, *key)
+		}
+		if len(info.HostKeys) == 0 && len(failures) > 0 {
+			info.HostKeyError = strings.Join(failures, "; ")
+		}
+	}
+
+	info.Weak = weakSSHAlgorithms(info)
+	return info, nil
+}
+
+// selectKex picks the server's most preferred key exchange that can be used to
+// obtain host keys.
+func selectKex(algorithms []string) string {
+	for _, algorithm := range algorithms {
+		if _, ok := sshCurves[algorithm]; ok {
+			return algorithm
+		}
+		if _, ok := sshGroups[algorithm]; ok {
+			return algorithm
+		}
+	}
+	return ""
+}
+
+func dialSSH(ctx context.Context, address string, timeout time.Duration) (*sshSession, error) {
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, err
+	}
+
+	session := &sshSession{conn: conn, reader: bufio.NewReader(conn)}
+
+	// SSH servers send their identification string immediately, so anything
+	// that stays silent or greets with something else is not worth waiting on.
+	conn.SetDeadline(time.Now().Add(min(timeout, sshBannerTimeout)))
+	line, err := session.reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "SSH-") {
+		conn.Close()
+		return nil, ErrNotSSH
+	}
+	session.banner = strings.TrimRight(line, "\r\n")
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	if _, err := conn.Write([]byte("SSH-2.0-bingus\r\n")); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	for {
+		payload, err := session.readPacket()
+		if err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if payload[0] == sshMsgKexInit {
+			session.serverInit = payload
+			return session, nil
+		}
+		if payload[0] != sshMsgIgnore && payload[0] != sshMsgDebug {
+			conn.Close()
+			return nil, fmt.Errorf("unexpected SSH message %d before KEXINIT", payload[0])
+		}
+	}
+}
+
+func fetchHostKey(ctx context.Context, address string, timeout time.Duration, kexAlgorithm, hostKeyAlgorithm string, server *kexInit) (*SSHHostKey, error) {
+	session, err := dialSSH(ctx, address, timeout)
+	if err != nil {
+		return nil, err
+	}
+	defer session.conn.Close()
+
+	client := *server
+	client.kex = []string{kexAlgorithm}
+	client.hostKey = []string{hostKeyAlgorithm}
+	if err := session.writePacket(marshalKexInit(&client)); err != nil {
+		return nil, err
+	}
+
+	// SSH_MSG_KEXDH_INIT and SSH_MSG_KEX_ECDH_INIT share a message number and
+	// only differ in how the client's public value is encoded.
+	kexInitMsg := []byte{sshMsgKexECDHInit}
+	if curve, ok := sshCurves[kexAlgorithm]; ok {
+		private, err := curve.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		kexInitMsg = appendSSHString(kexInitMsg, private.PublicKey().Bytes())
+	} else {
+		group := sshGroups[kexAlgorithm]
+		x, err := rand.Int(rand.Reader, new(big.Int).Sub(group, big.NewInt(3)))
+		if err != nil {
+			return nil, err
+		}
+		x.Add(x, big.NewInt(2))
+		e := new(big.Int).Exp(big.NewInt(2), x, group)
+		kexInitMsg = appendSSHMPInt(kexInitMsg, e)
+	}
+
+	if err := session.writePacket(kexInitMsg); err != nil {
+		return nil, err
+	}
+
+	for {
+		payload, err := session.readPacket()
+		if err != nil {
+			return nil, err
+		}
+		if payload[0] == sshMsgIgnore || payload[0] == sshMsgDebug {
+			continue
+		}
+		if payload[0] != sshMsgKexECDHReply {
+			return nil, fmt.Errorf("unexpected SSH message %d during key exchange", payload[0])
+		}
+
+		blob, _, err := readSSHString(payload[1:])
+		if err != nil {
+			return nil, err
+		}
+		keyType, _, err := readSSHString(blob)
+		if err != nil {
+			return nil, err
+		}
+
+		fingerprint := sha256.Sum256(blob)
+		return &SSHHostKey{
+			Type:        string(keyType),
+			Fingerprint: "SHA256:" + base64.RawStdEncoding.EncodeToString(fingerprint[:]),
+		}, nil
+	}
+}
+
+// hostKeyProbes returns one signature algorithm per distinct host key offered
+// by the server, since the RSA variants all share a single key.
+func hostKeyProbes(algorithms []string) []string {
+	var probes []string
+	seen := make(map[string]bool)
+
+	for _, algorithm := range algorithms {
+		if strings.Contains(algorithm, "-cert-") {
+			continue
+		}
+
+		family := algorithm
+		switch algorithm {
+		case "ssh-rsa", "rsa-sha2-256", "rsa-sha2-512":
+			family = "rsa"
+		}
+
+		if !seen[family] {
+			seen[family] = true
+			probes = append(probes, algorithm)
+		}
+	}
+
+	return probes
+}
+
+func parseSSHBanner(info *SSHInfo) {
+	ident := strings.TrimPrefix(info.Banner, "SSH-")
+	ident, info.Comments, _ = strings.Cut(ident, " ")
+	info.ProtocolVersion, info.SoftwareVersion, _ = strings.Cut(ident, "-")
+}
+
+func weakSSHAlgorithms(info *SSHInfo) []string {
+	var weak []string
+
+	if info.ProtocolVersion != "2.0" && info.ProtocolVersion != "1.99" {
+		weak = append(weak, "protocol:"+info.ProtocolVersion)
+	}
+
+	for _, algorithm := range info.KexAlgorithms {
+		if strings.HasSuffix(algorithm, "-sha1") || strings.Contains(algorithm, "group1-") || strings.HasPrefix(algorithm, "rsa1024") {
+			weak = append(weak, "kex:"+algorithm)
+		}
+	}
+
+	for _, algorithm := range info.HostKeyAlgorithms {
+		if strings.HasPrefix(algorithm, "ssh-rsa") || strings.HasPrefix(algorithm, "ssh-dss") {
+			weak = append(weak, "hostkey:"+algorithm)
+		}
+	}
+
+	for _, algorithm := range info.Ciphers {
+		if strings.HasSuffix(algorithm, "-cbc") || strings.HasPrefix(algorithm, "arcfour") || algorithm == "none" {
+			weak = append(weak, "cipher:"+algorithm)
+		}
+	}
+
+	for _, algorithm := range info.MACs {
+		if strings.Contains(algorithm, "md5") || strings.HasPrefix(algorithm, "hmac-sha1") ||
+			strings.HasPrefix(algorithm, "umac-64") || algorithm == "none" {
+			weak = append(weak, "mac:"+algorithm)
+		}
+	}
+
+	return weak
+}
+
+func (s *sshSession) readPacket() ([]byte, error) {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(s.reader, header); err != nil {
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header)
+	if length < 2 || length > maxSSHPacketSize {
+		return nil, fmt.Errorf("invalid SSH packet length %d", length)
+	}
+
+	packet := make([]byte, length)
+	if _, err := io.ReadFull(s.reader, packet); err != nil {
+		return nil, err
+	}
+
+	padding := int(packet[0])
+	if padding+2 > len(packet) {
+		return nil, fmt.Errorf("invalid SSH padding length %d", padding)
+	}
+	return packet[1 : len(packet)-padding], nil
+}
+
+func (s *sshSession) writePacket(payload []byte) error {
+	const blockSize = 8
+
+	padding := blockSize - (5+len(payload))%blockSize
+	if padding < 4 {
+		padding += blockSize
+	}
+
+	packet := binary.BigEndian.AppendUint32(nil, uint32(1+len(payload)+padding))
+	packet = append(packet, byte(padding))
+	packet = append(packet, payload...)
+	packet = append(packet, make([]byte, padding)...)
+
+	_, err := s.conn.Write(packet)
+	return err
+}
+
+func parseKexInit(payload []byte) (*kexInit, error) {
+	// Skip the message number and the 16 byte cookie.
+	if len(payload) < 17 {
+		return nil, fmt.Errorf("truncated KEXINIT")
+	}
+	data := payload[17:]
+
+	var lists [8][]string
+	for i := range lists {
+		value, rest, err := readSSHString(data)
+		if err != nil {
+			return nil, fmt.Errorf("malformed KEXINIT: %w", err)
+		}
+		if len(value) > 0 {
+			lists[i] = strings.Split(string(value), ",")
+		}
+		data = rest
+	}
+
+	return &kexInit{
+		kex:         lists[0],
+		hostKey:     lists[1],
+		ciphersCS:   lists[2],
+		ciphersSC:   lists[3],
+		macsCS:      lists[4],
+		macsSC:      lists[5],
+		compression: lists[6],
+		compSC:      lists[7],
+	}, nil
+}
+
+func marshalKexInit(offer *kexInit) []byte {
+	payload := []byte{sshMsgKexInit}
+
+	cookie := make([]byte, 16)
+	rand.Read(cookie)
+	payload = append(payload, cookie...)
+
+	for _, list := range [][]string{
+		offer.kex, offer.hostKey,
+		offer.ciphersCS, offer.ciphersSC,
+		offer.macsCS, offer.macsSC,
+		offer.compression, offer.compSC,
+		nil, nil,
+	} {
+		payload = appendSSHString(payload, []byte(strings.Join(list, ",")))
+	}
+
+	// first_kex_packet_follows and the reserved field.
+	payload = append(payload, 0)
+	return binary.BigEndian.AppendUint32(payload, 0)
+}
+
+func readSSHString(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("truncated SSH string")
+	}
+
+	length := binary.BigEndian.Uint32(data)
+	if uint64(length) > uint64(len(data)-4) {
+		return nil, nil, fmt.Errorf("truncated SSH string")
+	}
+	return data[4 : 4+length], data[4+length:], nil
+}
+
+func appendSSHString(data, value []byte) []byte {
+	data = binary.BigEndian.AppendUint32(data, uint32(len(value)))
+	return append(data, value...)
+}
+
+func appendSSHMPInt(data []byte, value *big.Int) []byte {
+	raw := value.Bytes()
+	if len(raw) > 0 && raw[0]&0x80 != 0 {
+		raw = append([]byte{0}, raw...)
+	}
+	return appendSSHString(data, raw)
+}
@@ -0,0 +1,226 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"io"
+	"net"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var testHostKey = appendSSHString(appendSSHString(nil, []byte("ssh-ed25519")), make([]byte, 32))
+
+// fakeSSHServer accepts connections on a local port and answers the
+// identification exchange, KEXINIT and a single (EC)DH init with testHostKey.
+func fakeSSHServer(t *testing.T, banner string, offer *kexInit) (string, int) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSSH(conn, banner, offer)
+		}
+	}()
+
+	host, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+	return host, port
+}
+
+func serveSSH(conn net.Conn, banner string, offer *kexInit) {
+	defer conn.Close()
+
+	session := &sshSession{conn: conn, reader: bufio.NewReader(conn)}
+	io.WriteString(conn, banner+"\r\n")
+	if _, err := session.reader.ReadString('\n'); err != nil {
+		return
+	}
+	if offer == nil {
+		return
+	}
+	session.writePacket(marshalKexInit(offer))
+
+	for {
+		payload, err := session.readPacket()
+		if err != nil {
+			return
+		}
+		if payload[0] == sshMsgKexECDHInit {
+			reply := []byte{sshMsgKexECDHReply}
+			reply = appendSSHString(reply, testHostKey)
+			reply = appendSSHString(reply, make([]byte, 32))
+			reply = appendSSHString(reply, []byte("signature"))
+			session.writePacket(reply)
+			return
+		}
+	}
+}
+
+func testFingerprint() string {
+	sum := sha256.Sum256(testHostKey)
+	return "SHA256:" + base64.RawStdEncoding.EncodeToString(sum[:])
+}
+
+func TestSSH(t *testing.T) {
+	for _, kex := range []string{"curve25519-sha256", "diffie-hellman-group14-sha256"} {
+		t.Run(kex, func(t *testing.T) {
+			offer := &kexInit{
+				kex:         []string{"sntrup761x25519-sha512@openssh.com", kex},
+				hostKey:     []string{"ssh-ed25519", "ssh-ed25519-cert-v01@openssh.com"},
+				ciphersCS:   []string{"aes128-ctr"},
+				ciphersSC:   []string{"aes128-ctr"},
+				macsCS:      []string{"hmac-sha2-256"},
+				macsSC:      []string{"hmac-sha2-256"},
+				compression: []string{"none"},
+				compSC:      []string{"none"},
+			}
+			host, port := fakeSSHServer(t, "SSH-2.0-OpenSSH_9.6 Ubuntu-3", offer)
+
+			info, err := SSH(context.Background(), host, port, 2*time.Second)
+			if err != nil {
+				t.Fatalf("SSH() error: %v", err)
+			}
+
+			if info.SoftwareVersion != "OpenSSH_9.6" || info.Comments != "Ubuntu-3" {
+				t.Errorf("SoftwareVersion, Comments = %q, %q", info.SoftwareVersion, info.Comments)
+			}
+			if len(info.HostKeys) != 1 || info.HostKeys[0].Type != "ssh-ed25519" || info.HostKeys[0].Fingerprint != testFingerprint() {
+				t.Errorf("HostKeys = %+v, want one ssh-ed25519 key %s (error %q)", info.HostKeys, testFingerprint(), info.HostKeyError)
+			}
+		})
+	}
+}
+
+func TestSSHUnsupportedKex(t *testing.T) {
+	offer := &kexInit{
+		kex:     []string{"diffie-hellman-group-exchange-sha256"},
+		hostKey: []string{"ssh-rsa"},
+	}
+	host, port := fakeSSHServer(t, "SSH-2.0-legacy", offer)
+
+	info, err := SSH(context.Background(), host, port, 2*time.Second)
+	if err != nil {
+		t.Fatalf("SSH() error: %v", err)
+	}
+	if len(info.HostKeys) != 0 || info.HostKeyError == "" {
+		t.Errorf("HostKeys, HostKeyError = %+v, %q; want an explanation", info.HostKeys, info.HostKeyError)
+	}
+}
+
+func TestSSHRejectsOtherBanners(t *testing.T) {
+	host, port := fakeSSHServer(t, "220 mail.example.com ESMTP", nil)
+
+	start := time.Now()
+	if _, err := SSH(context.Background(), host, port, 5*time.Second); !errors.Is(err, ErrNotSSH) {
+		t.Errorf("SSH() error = %v, want ErrNotSSH", err)
+	}
+	if elapsed := time.Since(start); elapsed > sshBannerTimeout {
+		t.Errorf("SSH() took %v to reject a non-SSH banner", elapsed)
+	}
+}
+
+func TestSSHSilentService(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err == nil {
+			defer conn.Close()
+			time.Sleep(3 * time.Second)
+		}
+	}()
+
+	host, portStr, _ := net.SplitHostPort(listener.Addr().String())
+	port, _ := strconv.Atoi(portStr)
+
+	start := time.Now()
+	if _, err := SSH(context.Background(), host, port, 5*time.Second); !errors.Is(err, ErrNotSSH) {
+		t.Errorf("SSH() error = %v, want ErrNotSSH", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*sshBannerTimeout {
+		t.Errorf("SSH() waited %v on a silent service", elapsed)
+	}
+}
+
+func TestKexInitRoundTrip(t *testing.T) {
+	offer := &kexInit{
+		kex:         []string{"curve25519-sha256", "diffie-hellman-group14-sha256"},
+		hostKey:     []string{"rsa-sha2-512", "ssh-ed25519"},
+		ciphersCS:   []string{"chacha20-poly1305@openssh.com"},
+		ciphersSC:   []string{"aes256-gcm@openssh.com"},
+		macsCS:      []string{"hmac-sha2-256"},
+		macsSC:      []string{"hmac-sha2-512"},
+		compression: []string{"none"},
+		compSC:      []string{"none", "zlib@openssh.com"},
+	}
+
+	got, err := parseKexInit(marshalKexInit(offer))
+	if err != nil {
+		t.Fatalf("parseKexInit() error: %v", err)
+	}
+	if !slices.Equal(got.kex, offer.kex) || !slices.Equal(got.hostKey, offer.hostKey) ||
+		!slices.Equal(got.ciphersSC, offer.ciphersSC) || !slices.Equal(got.macsSC, offer.macsSC) ||
+		!slices.Equal(got.compSC, offer.compSC) {
+		t.Errorf("parseKexInit() = %+v, want %+v", got, offer)
+	}
+
+	if _, err := parseKexInit([]byte{sshMsgKexInit, 1, 2, 3}); err == nil {
+		t.Error("parseKexInit() accepted a truncated message")
+	}
+}
+
+func TestParseSSHBanner(t *testing.T) {
+	info := &SSHInfo{Banner: "SSH-2.0-OpenSSH_8.9p1 Ubuntu-3ubuntu0.6"}
+	parseSSHBanner(info)
+
+	if info.ProtocolVersion != "2.0" || info.SoftwareVersion != "OpenSSH_8.9p1" || info.Comments != "Ubuntu-3ubuntu0.6" {
+		t.Errorf("parseSSHBanner() = %q, %q, %q", info.ProtocolVersion, info.SoftwareVersion, info.Comments)
+	}
+}
+
+func TestWeakSSHAlgorithms(t *testing.T) {
+	info := &SSHInfo{
+		ProtocolVersion:   "2.0",
+		KexAlgorithms:     []string{"curve25519-sha256", "diffie-hellman-group1-sha1", "diffie-hellman-group14-sha256"},
+		HostKeyAlgorithms: []string{"ssh-ed25519", "ssh-rsa", "rsa-sha2-512"},
+		Ciphers:           []string{"aes128-ctr", "aes128-cbc"},
+		MACs:              []string{"hmac-sha2-256", "hmac-md5", "hmac-sha1"},
+	}
+
+	want := []string{
+		"kex:diffie-hellman-group1-sha1",
+		"hostkey:ssh-rsa",
+		"cipher:aes128-cbc",
+		"mac:hmac-md5",
+		"mac:hmac-sha1",
+	}
+	if got := weakSSHAlgorithms(info); !slices.Equal(got, want) {
+		t.Errorf("weakSSHAlgorithms() = %v, want %v", got, want)
+	}
+}
+
+func TestHostKeyProbes(t *testing.T) {
+	got := hostKeyProbes([]string{"rsa-sha2-512", "rsa-sha2-256", "ssh-rsa", "ecdsa-sha2-nistp256", "ssh-ed25519-cert-v01@openssh.com", "ssh-ed25519"})
+	want := []string{"rsa-sha2-512", "ecdsa-sha2-nistp256", "ssh-ed25519"}
+	if !slices.Equal(got, want) {
+		t.Errorf("hostKeyProbes() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,417 @@
+package probe
+
+import (
+	"bufio"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var implicitTLSPorts = map[int]bool{
+	443:  true,
+	465:  true,
+	636:  true,
+	853:  true,
+	993:  true,
+	995:  true,
+	8443: true,
+}
+
+var startTLSPorts = map[int]string{
+	21:   "ftp",
+	25:   "smtp",
+	110:  "pop3",
+	143:  "imap",
+	389:  "ldap",
+	587:  "smtp",
+	5432: "postgresql",
+}
+
+var startTLSUpgraders = map[string]func(conn net.Conn) (bool, error){
+	"ftp":        upgradeFTP,
+	"smtp":       upgradeSMTP,
+	"pop3":       upgradePOP3,
+	"imap":       upgradeIMAP,
+	"ldap":       upgradeLDAP,
+	"postgresql": upgradePostgreSQL,
+}
+
+func SupportsTLS(port int) bool {
+	_, ok := startTLSPorts[port]
+	return ok || implicitTLSPorts[port]
+}
+
+func TLS(ctx context.Context, host string, port int, timeout time.Duration) (*TLSInfo, error) {
+	protocol, startTLS := startTLSPorts[port]
+	if !startTLS && !implicitTLSPorts[port] {
+		return nil, fmt.Errorf("no TLS probe for port %d", port)
+	}
+
+	dialer := net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(host, strconv.Itoa(port)))
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	info := &TLSInfo{Protocol: protocol, StartTLS: startTLS, Offered: true}
+
+	if startTLS {
+		offered, err := startTLSUpgraders[protocol](conn)
+		info.Offered = offered
+		if err != nil {
+			info.Error = fmt.Sprintf("STARTTLS negotiation failed: %v", err)
+			return info, nil
+		}
+		if !offered {
+			return info, nil
+		}
+	}
+
+	config := &tls.Config{InsecureSkipVerify: true}
+	if net.ParseIP(host) == nil {
+		config.ServerName = host
+	}
+
+	tlsConn := tls.Client(conn, config)
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		info.Error = fmt.Sprintf("TLS handshake failed: %v", err)
+		return info, nil
+	}
+
+	state := tlsConn.ConnectionState()
+	info.Version = tls.VersionName(state.Version)
+	info.CipherSuite = tls.CipherSuiteName(state.CipherSuite)
+
+	if len(state.PeerCertificates) > 0 {
+		cert := state.PeerCertificates[0]
+		fingerprint := sha256.Sum256(cert.Raw)
+		info.Certificate = &Certificate{
+			Subject:   cert.Subject.String(),
+			Issuer:    cert.Issuer.String(),
+			DNSNames:  cert.DNSNames,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			SHA256:    hex.EncodeToString(fingerprint[:]),
+		}
+	}
+
+	return info, nil
+}
+
+func upgradeFTP(conn net.Conn) (bool, error) {
+	tp := textproto.NewConn(conn)
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return false, err
+	}
+
+	if err := tp.PrintfLine("AUTH TLS"); err != nil {
+		return false, err
+	}
+
+	code, _, err := tp.ReadResponse(0)
+	if err != nil && code == 0 {
+		return false, err
+	}
+	return code == 234, nil
+}
+
+func upgradeSMTP(conn net.Conn) (bool, error) {
+	tp := textproto.NewConn(conn)
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return false, err
+	}
+
+	if err := tp.PrintfLine("EHLO bingus"); err != nil {
+		return false, err
+	}
+
+	_, extensions, err := tp.ReadResponse(250)
+	if err != nil {
+		return false, err
+	}
+
+	offered := false
+	for _, ext := range strings.Split(extensions, "\n") {
+		if strings.EqualFold(strings.TrimSpace(ext), "STARTTLS") {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		return false, nil
+	}
+
+	if err := tp.PrintfLine("STARTTLS"); err != nil {
+		return true, err
+	}
+
+	if _, _, err := tp.ReadResponse(220); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+func upgradePOP3(conn net.Conn) (bool, error) {
+	tp := textproto.NewConn(conn)
+	greeting, err := tp.ReadLine()
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasPrefix(greeting, "+OK") {
+		return false, fmt.Errorf("unexpected POP3 greeting: %s", greeting)
+	}
+
+	if err := tp.PrintfLine("CAPA"); err != nil {
+		return false, err
+	}
+
+	status, err := tp.ReadLine()
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasPrefix(status, "+OK") {
+		return false, nil
+	}
+
+	capabilities, err := tp.ReadDotLines()
+	if err != nil {
+		return false, err
+	}
+
+	offered := false
+	for _, capability := range capabilities {
+		if strings.EqualFold(strings.TrimSpace(capability), "STLS") {
+			offered = true
+			break
+		}
+	}
+	if !offered {
+		return false, nil
+	}
+
+	if err := tp.PrintfLine("STLS"); err != nil {
+		return true, err
+	}
+
+	reply, err := tp.ReadLine()
+	if err != nil {
+		return true, err
+	}
+	if !strings.HasPrefix(reply, "+OK") {
+		return true, fmt.Errorf("STLS rejected: %s", reply)
+	}
+	return true, nil
+}
+
+func upgradeIMAP(conn net.Conn) (bool, error) {
+	tp := textproto.NewConn(conn)
+	greeting, err := tp.ReadLine()
+	if err != nil {
+		return false, err
+	}
+	if !strings.HasPrefix(greeting, "* OK") && !strings.HasPrefix(greeting, "* PREAUTH") {
+		return false, fmt.Errorf("unexpected IMAP greeting: %s", greeting)
+	}
+
+	if err := tp.PrintfLine("a001 CAPABILITY"); err != nil {
+		return false, err
+	}
+
+	offered := false
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return false, err
+		}
+		if strings.HasPrefix(line, "* CAPABILITY") && strings.Contains(strings.ToUpper(line), " STARTTLS") {
+			offered = true
+		}
+		if strings.HasPrefix(line, "a001 ") {
+			break
+		}
+	}
+	if !offered {
+		return false, nil
+	}
+
+	if err := tp.PrintfLine("a002 STARTTLS"); err != nil {
+		return true, err
+	}
+
+	for {
+		line, err := tp.ReadLine()
+		if err != nil {
+			return true, err
+		}
+		if strings.HasPrefix(line, "a002 ") {
+			if !strings.HasPrefix(line, "a002 OK") {
+				return true, fmt.Errorf("STARTTLS rejected: %s", line)
+			}
+			return true, nil
+		}
+	}
+}
+
+// ldapStartTLSRequest is a BER encoded ExtendedRequest (message ID 1) for the
+// StartTLS OID 1.3.6.1.4.1.1466.20037.
+var ldapStartTLSRequest = []byte{
+	0x30, 0x1d, 0x02, 0x01, 0x01, 0x77, 0x18, 0x80, 0x16,
+	'1', '.', '3', '.', '6', '.', '1', '.', '4', '.', '1', '.',
+	'1', '4', '6', '6', '.', '2', '0', '0', '3', '7',
+}
+
+const (
+	ldapResultSuccess       = 0
+	ldapResultProtocolError = 2
+
+	// maxLDAPMessageSize bounds the StartTLS response; real ones are a few
+	// dozen bytes.
+	maxLDAPMessageSize = 64 << 10
+)
+
+func upgradeLDAP(conn net.Conn) (bool, error) {
+	if _, err := conn.Write(ldapStartTLSRequest); err != nil {
+		return false, err
+	}
+
+	reader := bufio.NewReader(conn)
+	message, err := readBER(reader)
+	if err != nil {
+		return false, err
+	}
+
+	// LDAPMessage ::= SEQUENCE { messageID INTEGER, protocolOp ExtendedResponse }
+	_, _, rest, err := splitBER(message)
+	if err != nil {
+		return false, err
+	}
+	tag, op, _, err := splitBER(rest)
+	if err != nil {
+		return false, err
+	}
+	if tag != 0x78 {
+		return false, fmt.Errorf("unexpected LDAP response tag 0x%02x", tag)
+	}
+
+	tag, code, _, err := splitBER(op)
+	if err != nil {
+		return false, err
+	}
+	if tag != 0x0a || len(code) != 1 {
+		return false, fmt.Errorf("malformed LDAP result code")
+	}
+
+	switch code[0] {
+	case ldapResultSuccess:
+		return true, nil
+	case ldapResultProtocolError:
+		// Servers without StartTLS reject the unknown extended operation.
+		return false, nil
+	default:
+		return true, fmt.Errorf("StartTLS rejected with LDAP result code %d", code[0])
+	}
+}
+
+func readBER(reader *bufio.Reader) ([]byte, error) {
+	tag, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	if tag != 0x30 {
+		return nil, fmt.Errorf("unexpected BER tag 0x%02x", tag)
+	}
+
+	length, err := reader.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+
+	size := int(length)
+	if length&0x80 != 0 {
+		octets := int(length & 0x7f)
+		if octets == 0 || octets > 4 {
+			return nil, fmt.Errorf("unsupported BER length encoding")
+		}
+		size = 0
+		for range octets {
+			b, err := reader.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			size = size<<8 | int(b)
+		}
+	}
+	if size > maxLDAPMessageSize {
+		return nil, fmt.Errorf("LDAP message length %d is too large", size)
+	}
+
+	body := make([]byte, size)
+	if _, err := io.ReadFull(reader, body); err != nil {
+		return nil, err
+	}
+	return body, nil
+}
+
+// splitBER returns the tag and contents of the first element in data along
+// with the bytes that follow it.
+func splitBER(data []byte) (byte, []byte, []byte, error) {
+	if len(data) < 2 {
+		return 0, nil, nil, fmt.Errorf("truncated BER element")
+	}
+
+	tag := data[0]
+	size := int(data[1])
+	offset := 2
+	if data[1]&0x80 != 0 {
+		octets := int(data[1] & 0x7f)
+		if octets == 0 || octets > 4 || len(data) < 2+octets {
+			return 0, nil, nil, fmt.Errorf("unsupported BER length encoding")
+		}
+		size = 0
+		for _, b := range data[2 : 2+octets] {
+			size = size<<8 | int(b)
+		}
+		offset += octets
+	}
+
+	if len(data) < offset+size {
+		return 0, nil, nil, fmt.Errorf("truncated BER element")
+	}
+	return tag, data[offset : offset+size], data[offset+size:], nil
+}
+
+func upgradePostgreSQL(conn net.Conn) (bool, error) {
+	request := make([]byte, 8)
+	binary.BigEndian.PutUint32(request[0:4], 8)
+	binary.BigEndian.PutUint32(request[4:8], 80877103)
+
+	if _, err := conn.Write(request); err != nil {
+		return false, err
+	}
+
+	reply := make([]byte, 1)
+	if _, err := io.ReadFull(conn, reply); err != nil {
+		return false, err
+	}
+
+	switch reply[0] {
+	case 'S':
+		return true, nil
+	case 'N':
+		return false, nil
+	default:
+		return false, fmt.Errorf("unexpected PostgreSQL SSLRequest reply: %q", reply[0])
+	}
+}
@@ -0,0 +1,269 @@
+package probe
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+)
+
+// serve runs handler as the server side of an in-memory connection and
+// returns the client side.
+func serve(t *testing.T, handler func(conn net.Conn, reader *bufio.Reader)) net.Conn {
+	t.Helper()
+
+	client, server := net.Pipe()
+	go func() {
+		defer server.Close()
+		handler(server, bufio.NewReader(server))
+	}()
+	t.Cleanup(func() { client.Close() })
+
+	return client
+}
+
+func expectLine(t *testing.T, reader *bufio.Reader, want string) {
+	t.Helper()
+
+	line, err := reader.ReadString('\n')
+	if err != nil {
+		t.Errorf("reading %q: %v", want, err)
+		return
+	}
+	if got := strings.TrimRight(line, "\r\n"); got != want {
+		t.Errorf("client sent %q, want %q", got, want)
+	}
+}
+
+func TestUpgradeSMTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		extensions  string
+		reply       string
+		wantOffered bool
+		wantErr     bool
+	}{
+		{"offered", "250-mail.example.com\r\n250-SIZE 1000\r\n250 STARTTLS\r\n", "220 go ahead\r\n", true, false},
+		{"not offered", "250-mail.example.com\r\n250 SIZE 1000\r\n", "", false, false},
+		{"rejected", "250-mail.example.com\r\n250 STARTTLS\r\n", "454 TLS not available\r\n", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := serve(t, func(conn net.Conn, reader *bufio.Reader) {
+				io.WriteString(conn, "220 mail.example.com ESMTP\r\n")
+				expectLine(t, reader, "EHLO bingus")
+				io.WriteString(conn, tt.extensions)
+				if tt.reply != "" {
+					expectLine(t, reader, "STARTTLS")
+					io.WriteString(conn, tt.reply)
+				}
+			})
+
+			offered, err := upgradeSMTP(conn)
+			if offered != tt.wantOffered || (err != nil) != tt.wantErr {
+				t.Errorf("upgradeSMTP() = %v, %v; want %v, error %v", offered, err, tt.wantOffered, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpgradeIMAP(t *testing.T) {
+	tests := []struct {
+		name        string
+		greeting    string
+		capability  string
+		reply       string
+		wantOffered bool
+		wantErr     bool
+	}{
+		{"offered", "* OK IMAP4rev1 ready", "* CAPABILITY IMAP4rev1 STARTTLS LOGINDISABLED", "a002 OK begin TLS", true, false},
+		{"preauth", "* PREAUTH IMAP4rev1 logged in", "* CAPABILITY IMAP4rev1 STARTTLS", "a002 OK begin TLS", true, false},
+		{"not offered", "* OK IMAP4rev1 ready", "* CAPABILITY IMAP4rev1 AUTH=PLAIN", "", false, false},
+		{"rejected", "* OK IMAP4rev1 ready", "* CAPABILITY IMAP4rev1 STARTTLS", "a002 NO unavailable", true, true},
+		{"bye", "* BYE shutting down", "", "", false, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := serve(t, func(conn net.Conn, reader *bufio.Reader) {
+				io.WriteString(conn, tt.greeting+"\r\n")
+				if tt.capability == "" {
+					return
+				}
+				expectLine(t, reader, "a001 CAPABILITY")
+				io.WriteString(conn, tt.capability+"\r\na001 OK done\r\n")
+				if tt.reply != "" {
+					expectLine(t, reader, "a002 STARTTLS")
+					io.WriteString(conn, tt.reply+"\r\n")
+				}
+			})
+
+			offered, err := upgradeIMAP(conn)
+			if offered != tt.wantOffered || (err != nil) != tt.wantErr {
+				t.Errorf("upgradeIMAP() = %v, %v; want %v, error %v", offered, err, tt.wantOffered, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpgradePOP3(t *testing.T) {
+	tests := []struct {
+		name         string
+		capabilities string
+		reply        string
+		wantOffered  bool
+		wantErr      bool
+	}{
+		{"offered", "+OK\r\nUSER\r\nSTLS\r\n.\r\n", "+OK begin TLS", true, false},
+		{"not offered", "+OK\r\nUSER\r\n.\r\n", "", false, false},
+		{"rejected", "+OK\r\nSTLS\r\n.\r\n", "-ERR not now", true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := serve(t, func(conn net.Conn, reader *bufio.Reader) {
+				io.WriteString(conn, "+OK POP3 ready\r\n")
+				expectLine(t, reader, "CAPA")
+				io.WriteString(conn, tt.capabilities)
+				if tt.reply != "" {
+					expectLine(t, reader, "STLS")
+					io.WriteString(conn, tt.reply+"\r\n")
+				}
+			})
+
+			offered, err := upgradePOP3(conn)
+			if offered != tt.wantOffered || (err != nil) != tt.wantErr {
+				t.Errorf("upgradePOP3() = %v, %v; want %v, error %v", offered, err, tt.wantOffered, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestUpgradeFTP(t *testing.T) {
+	tests := []struct {
+		name        string
+		greeting    string
+		reply       string
+		wantOffered bool
+	}{
+		{"offered", "220-Welcome\r\n220 FTP ready\r\n", "234 AUTH TLS OK", true},
+		{"not offered", "220 FTP ready\r\n", "502 Command not implemented", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := serve(t, func(conn net.Conn, reader *bufio.Reader) {
+				io.WriteString(conn, tt.greeting)
+				expectLine(t, reader, "AUTH TLS")
+				io.WriteString(conn, tt.reply+"\r\n")
+			})
+
+			offered, err := upgradeFTP(conn)
+			if err != nil || offered != tt.wantOffered {
+				t.Errorf("upgradeFTP() = %v, %v; want %v", offered, err, tt.wantOffered)
+			}
+		})
+	}
+}
+
+func TestUpgradePostgreSQL(t *testing.T) {
+	for reply, want := range map[byte]bool{'S': true, 'N': false} {
+		conn := serve(t, func(conn net.Conn, reader *bufio.Reader) {
+			request := make([]byte, 8)
+			if _, err := io.ReadFull(reader, request); err != nil {
+				t.Errorf("reading SSLRequest: %v", err)
+				return
+			}
+			if binary.BigEndian.Uint32(request[0:4]) != 8 || binary.BigEndian.Uint32(request[4:8]) != 80877103 {
+				t.Errorf("unexpected SSLRequest %x", request)
+			}
+			conn.Write([]byte{reply})
+		})
+
+		offered, err := upgradePostgreSQL(conn)
+		if err != nil || offered != want {
+			t.Errorf("upgradePostgreSQL() with reply %q = %v, %v; want %v", reply, offered, err, want)
+		}
+	}
+}
+
+func ldapExtendedResponse(code byte) []byte {
+	// ExtendedResponse { resultCode, matchedDN "", diagnosticMessage "" }
+	op := []byte{0x78, 0x07, 0x0a, 0x01, code, 0x04, 0x00, 0x04, 0x00}
+	message := append([]byte{0x02, 0x01, 0x01}, op...)
+	return append([]byte{0x30, byte(len(message))}, message...)
+}
+
+func TestUpgradeLDAP(t *testing.T) {
+	tests := []struct {
+		name        string
+		code        byte
+		wantOffered bool
+		wantErr     bool
+	}{
+		{"success", ldapResultSuccess, true, false},
+		{"unsupported", ldapResultProtocolError, false, false},
+		{"unavailable", 52, true, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conn := serve(t, func(conn net.Conn, reader *bufio.Reader) {
+				request := make([]byte, len(ldapStartTLSRequest))
+				if _, err := io.ReadFull(reader, request); err != nil {
+					t.Errorf("reading StartTLS request: %v", err)
+					return
+				}
+				if !bytes.Equal(request, ldapStartTLSRequest) {
+					t.Errorf("unexpected StartTLS request %x", request)
+				}
+				conn.Write(ldapExtendedResponse(tt.code))
+			})
+
+			offered, err := upgradeLDAP(conn)
+			if offered != tt.wantOffered || (err != nil) != tt.wantErr {
+				t.Errorf("upgradeLDAP() = %v, %v; want %v, error %v", offered, err, tt.wantOffered, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestReadBERLongLength(t *testing.T) {
+	body := bytes.Repeat([]byte{0x04}, 300)
+	data := append([]byte{0x30, 0x82, 0x01, 0x2c}, body...)
+
+	got, err := readBER(bufio.NewReader(bytes.NewReader(data)))
+	if err != nil {
+		t.Fatalf("readBER() error: %v", err)
+	}
+	if len(got) != 300 {
+		t.Errorf("readBER() returned %d bytes, want 300", len(got))
+	}
+}
+
+func TestReadBERTooLarge(t *testing.T) {
+	// A 4 GiB length must fail before anything is allocated for it.
+	data := []byte{0x30, 0x84, 0xff, 0xff, 0xff, 0xff, 0x04}
+
+	if _, err := readBER(bufio.NewReader(bytes.NewReader(data))); err == nil {
+		t.Error("readBER() accepted a message longer than maxLDAPMessageSize")
+	}
+}
+
+func TestSplitBER(t *testing.T) {
+	tag, contents, rest, err := splitBER([]byte{0x02, 0x01, 0x07, 0x0a, 0x01, 0x00})
+	if err != nil {
+		t.Fatalf("splitBER() error: %v", err)
+	}
+	if tag != 0x02 || !bytes.Equal(contents, []byte{0x07}) || !bytes.Equal(rest, []byte{0x0a, 0x01, 0x00}) {
+		t.Errorf("splitBER() = %#x, %x, %x", tag, contents, rest)
+	}
+
+	if _, _, _, err := splitBER([]byte{0x04, 0x05, 0x00}); err == nil {
+		t.Error("splitBER() accepted a truncated element")
+	}
+}
@@ -0,0 +1,55 @@
+package probe
+
+import (
+	"time"
+)
+
+type Certificate struct {
+	Subject   string
+	Issuer    string
+	DNSNames  []string
+	NotBefore time.Time
+	NotAfter  time.Time
+	SHA256    string
+}
+
+type TLSInfo struct {
+	Protocol    string
+	StartTLS    bool
+	Offered     bool
+	Version     string
+	CipherSuite string
+	Certificate *Certificate
+	Error       string
+}
+
+type HTTPInfo struct {
+	URL           string
+	StatusCode    int
+	Server        string
+	PoweredBy     string
+	Title         string
+	Redirects     []string
+	ContentLength int64
+	FaviconHash   string
+}
+
+type SSHHostKey struct {
+	Type        string
+	Fingerprint string
+}
+
+type SSHInfo struct {
+	Banner            string
+	ProtocolVersion   string
+	SoftwareVersion   string
+	Comments          string
+	KexAlgorithms     []string
+	HostKeyAlgorithms []string
+	Ciphers           []string
+	MACs              []string
+	Compression       []string
+	HostKeys          []SSHHostKey
+	HostKeyError      string
+	Weak              []string
+}
@@ -0,0 +1,86 @@
+package report
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// csvTimeFormat is RFC 3339 with milliseconds, which spreadsheets parse.
+const csvTimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// CSVHeader is the header row of WriteCSV. Columns are only ever added at the
+// end, so spreadsheets can rely on their positions.
+var CSVHeader = []string{"host", "hostname", "port", "protocol", "state", "service", "banner", "rtt_ms", "timestamp"}
+
+// WriteCSV writes one row per port in the report, usually the open ones, and
+// one row with an empty port for each host that has none, whose state is the
+// host's status. Unanswered ports have an empty RTT.
+func (r *Report) WriteCSV(w io.Writer) error {
+	cw := csv.NewWriter(w)
+	cw.Write(CSVHeader)
+
+	for _, host := range r.Hosts {
+		if len(host.Ports) == 0 {
+			var rtt string
+			if host.RTT != nil {
+				rtt = formatMillis(*host.RTT)
+			}
+			cw.Write(csvRow(host.Address, host.Hostname, "", "", host.Status, "", "", rtt, r.End))
+			continue
+		}
+		for _, port := range host.Ports {
+			var rtt string
+			if port.RTT > 0 {
+				rtt = formatMillis(port.RTT)
+			}
+			cw.Write(csvRow(host.Address, host.Hostname, strconv.Itoa(port.Port), port.Protocol, port.State,
+				port.Service, port.Banner(), rtt, port.Time))
+		}
+	}
+
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return fmt.Errorf("error writing CSV report: %w", err)
+	}
+	return nil
+}
+
+func csvRow(host, hostname, port, protocol, state, service, banner, rtt string, at time.Time) []string {
+	var timestamp string
+	if !at.IsZero() {
+		timestamp = at.UTC().Format(csvTimeFormat)
+	}
+	return []string{host, csvText(hostname), port, protocol, state, csvText(service), csvText(banner), rtt, timestamp}
+}
+
+// csvText keeps text from scanned hosts, such as banners, from being taken
+// for a formula when the CSV is opened in a spreadsheet.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+func formatMillis(ms float64) string {
+	return strconv.FormatFloat(ms, 'f', -1, 64)
+}
+
+// Banner is the port's most telling self-description: the SSH banner, or the
+// HTTP Server header, or the page title.
+func (p Port) Banner() string {
+	switch {
+	case p.SSH != nil:
+		return p.SSH.Banner
+	case p.HTTP != nil && p.HTTP.Server != "":
+		return p.HTTP.Server
+	case p.HTTP != nil:
+		return p.HTTP.Title
+	default:
+		return ""
+	}
+}
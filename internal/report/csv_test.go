@@ -0,0 +1,86 @@
+package report
+
+import (
+	"bytes"
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jspback/bingus/internal/probe"
+	"github.com/jspback/bingus/internal/scan"
+)
+
+func TestWriteCSV(t *testing.T) {
+	targets, results, summary := portScan()
+	results["10.0.0.5"][2].SSH = &probe.SSHInfo{Banner: "SSH-2.0-OpenSSH_9.6"}
+	results["10.0.0.5"][0].HTTP = &probe.HTTPInfo{Server: "=HYPERLINK(\"http://evil\")"}
+
+	var buf bytes.Buffer
+	if err := Ports(Meta{}, targets, results, summary).WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error: %v", err)
+	}
+
+	rows, err := csv.NewReader(&buf).ReadAll()
+	if err != nil {
+		t.Fatalf("invalid CSV: %v", err)
+	}
+	if strings.Join(rows[0], ",") != "host,hostname,port,protocol,state,service,banner,rtt_ms,timestamp" {
+		t.Errorf("header = %v", rows[0])
+	}
+
+	want := [][]string{
+		{"10.0.0.5", "db01", "22", "tcp", "open", "ssh", "SSH-2.0-OpenSSH_9.6", "1.5", "2026-01-02T03:04:05.000Z"},
+		{"10.0.0.5", "db01", "5432", "tcp", "open", "postgresql", `'=HYPERLINK("http://evil")`, "2", "2026-01-02T03:04:05.000Z"},
+		{"10.0.0.5", "db01", "53", "udp", "open", "domain", "", "", "2026-01-02T03:04:05.000Z"},
+		{"10.0.0.7", "", "", "", "unknown", "", "", "", "2026-01-02T03:04:06.500Z"},
+		{"10.0.0.10", "web", "", "", "up", "", "", "1", "2026-01-02T03:04:06.500Z"},
+		{"2001:db8::1", "", "", "", "unknown", "", "", "", "2026-01-02T03:04:06.500Z"},
+	}
+	if len(rows)-1 != len(want) {
+		t.Fatalf("got %d rows, want %d:\n%s", len(rows)-1, len(want), buf.String())
+	}
+	for i, row := range want {
+		if got := strings.Join(rows[i+1], "|"); got != strings.Join(row, "|") {
+			t.Errorf("row %d = %s, want %s", i+1, got, strings.Join(row, "|"))
+		}
+	}
+}
+
+func TestWriteCSVAllStates(t *testing.T) {
+	targets, results, summary := portScan()
+	results["10.0.0.7"] = append(results["10.0.0.7"],
+		scan.PortResult{Host: "10.0.0.7", Port: 443, Proto: "tcp", Service: "https", State: scan.StateFiltered, Attempts: 2, Time: start})
+	results["10.0.0.10"] = append(results["10.0.0.10"],
+		scan.PortResult{Host: "10.0.0.10", Hostname: "web", Port: 22, Proto: "tcp", Service: "ssh", State: scan.StateClosed, RTT: time.Millisecond, Attempts: 1, Time: start})
+
+	r := Ports(Meta{}, targets, results, summary)
+	if r.Hosts[1].Address != "10.0.0.7" || r.Hosts[1].Status != StatusUnknown {
+		t.Errorf("10.0.0.7 = %+v, want its status unknown with only a filtered port", r.Hosts[1])
+	}
+
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error: %v", err)
+	}
+	for _, row := range []string{
+		"10.0.0.7,,443,tcp,filtered,https,,,2026-01-02T03:04:05.000Z\n",
+		"10.0.0.10,web,22,tcp,closed,ssh,,1,2026-01-02T03:04:05.000Z\n",
+	} {
+		if !strings.Contains(buf.String(), row) {
+			t.Errorf("CSV = %q, want a row %q", buf.String(), row)
+		}
+	}
+}
+
+func TestWriteCSVHostDiscovery(t *testing.T) {
+	r := Hosts(Meta{}, []scan.HostResult{{IP: "192.168.1.7", RTT: 1200 * time.Microsecond}}, scan.Summary{Start: start, End: end})
+
+	var buf bytes.Buffer
+	if err := r.WriteCSV(&buf); err != nil {
+		t.Fatalf("WriteCSV() error: %v", err)
+	}
+	if want := "192.168.1.7,,,,up,,,1.2,2026-01-02T03:04:06.500Z\n"; !strings.HasSuffix(buf.String(), want) {
+		t.Errorf("CSV = %q, want a row %q", buf.String(), want)
+	}
+}
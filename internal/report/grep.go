@@ -0,0 +1,90 @@
+package report
+
+import (
+	"bufio"
+	"cmp"
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+)
+
+// WriteGrep writes one line per host with its status, open ports and the
+// counts of the other states, for grep and awk:
+//
+//	Host: 10.0.0.5 (db01) Status: Up Ports: 22/open/tcp//ssh, 5432/open/tcp//postgresql Ignored State: closed (998)
+//
+// Ports are port/state/protocol//service, as in nmap's grepable output.
+// Comment lines starting with # open and close the output.
+func (r *Report) WriteGrep(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# %s %s scan initiated %s as: %s\n",
+		Scanner, r.Version, r.Start.Format(nmapTimeFormat), strings.Join(append([]string{Scanner}, r.Args...), " "))
+
+	states := make(map[string]map[string]int)
+	for _, host := range r.Stats.HostTimes {
+		states[host.Address] = host.States
+	}
+
+	up := 0
+	for _, host := range r.Hosts {
+		if host.Status == StatusUp {
+			up++
+		}
+		fmt.Fprintln(bw, grepLine(host, states[host.Address]))
+	}
+
+	total := len(r.Hosts)
+	if r.Command == "ping" {
+		total = r.Stats.Hosts
+	}
+	var interrupted string
+	if r.Interrupted {
+		interrupted = " (interrupted)"
+	}
+	fmt.Fprintf(bw, "# %s done at %s -- %d IP addresses (%d hosts up) scanned in %.2f seconds%s\n",
+		Scanner, r.End.Format(nmapTimeFormat), total, up, r.End.Sub(r.Start).Seconds(), interrupted)
+
+	if err := bw.Flush(); err != nil {
+		return fmt.Errorf("error writing grepable report: %w", err)
+	}
+	return nil
+}
+
+func grepLine(host Host, states map[string]int) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Host: %s (%s) Status: %s", host.Address, host.Hostname, grepStatus(host.Status))
+
+	if len(host.Ports) > 0 {
+		ports := make([]string, len(host.Ports))
+		for i, port := range host.Ports {
+			ports[i] = fmt.Sprintf("%d/%s/%s//%s", port.Port, port.State, port.Protocol, port.Service)
+		}
+		fmt.Fprintf(&b, " Ports: %s", strings.Join(ports, ", "))
+	}
+
+	var ignored []string
+	for _, state := range slices.SortedFunc(maps.Keys(states), func(a, b string) int {
+		if c := cmp.Compare(states[b], states[a]); c != 0 {
+			return c
+		}
+		return cmp.Compare(a, b)
+	}) {
+		if state != "open" && states[state] > 0 {
+			ignored = append(ignored, fmt.Sprintf("%s (%d)", state, states[state]))
+		}
+	}
+	if len(ignored) > 0 {
+		fmt.Fprintf(&b, " Ignored State: %s", strings.Join(ignored, ", "))
+	}
+	return b.String()
+}
+
+// grepStatus capitalises a host status the way nmap prints it.
+func grepStatus(status string) string {
+	if status == "" {
+		return status
+	}
+	return strings.ToUpper(status[:1]) + status[1:]
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/jspback/bingus/internal/scan"
+)
+
+func TestWriteGrep(t *testing.T) {
+	targets, results, summary := portScan()
+	summary.Stats.Hosts[0].States = map[string]int{"closed": 2, "filtered": 1}
+	summary.Stats.Hosts[1].States = map[string]int{"filtered": 3}
+
+	var buf bytes.Buffer
+	if err := Ports(Meta{Version: "v1.0.0", Command: "port", Args: []string{"port", "-H", "db01"}}, targets, results, summary).WriteGrep(&buf); err != nil {
+		t.Fatalf("WriteGrep() error: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	want := []string{
+		"Host: 10.0.0.5 (db01) Status: Up Ports: 22/open/tcp//ssh, 5432/open/tcp//postgresql, 53/open/udp//domain",
+		"Host: 10.0.0.7 () Status: Unknown Ignored State: filtered (3)",
+		"Host: 10.0.0.10 (web) Status: Up Ignored State: closed (2), filtered (1)",
+		"Host: 2001:db8::1 () Status: Unknown",
+	}
+	if len(lines) != len(want)+2 {
+		t.Fatalf("got %d lines, want %d hosts between two comments:\n%s", len(lines), len(want), buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "# bingus v1.0.0 scan initiated ") || !strings.HasSuffix(lines[0], " as: bingus port -H db01") {
+		t.Errorf("header = %q", lines[0])
+	}
+	for i, line := range want {
+		if lines[i+1] != line {
+			t.Errorf("line %d = %q, want %q", i+1, lines[i+1], line)
+		}
+	}
+	if footer := lines[len(lines)-1]; !strings.HasSuffix(footer, "-- 4 IP addresses (2 hosts up) scanned in 1.50 seconds") {
+		t.Errorf("footer = %q", footer)
+	}
+}
+
+func TestWriteGrepHostDiscovery(t *testing.T) {
+	r := Hosts(Meta{Command: "ping"}, []scan.HostResult{{IP: "192.168.1.7"}},
+		scan.Summary{Hosts: 254, Start: start, End: end, Err: context.Canceled})
+
+	var buf bytes.Buffer
+	if err := r.WriteGrep(&buf); err != nil {
+		t.Fatalf("WriteGrep() error: %v", err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "\nHost: 192.168.1.7 () Status: Up\n") {
+		t.Errorf("no host line in:\n%s", out)
+	}
+	if !strings.Contains(out, "254 IP addresses (1 hosts up) scanned in 1.50 seconds (interrupted)\n") {
+		t.Errorf("footer does not report the interruption:\n%s", out)
+	}
+}
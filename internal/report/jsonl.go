@@ -0,0 +1,149 @@
+package report
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/jspback/bingus/internal/scan"
+)
+
+// Event types of a JSON Lines stream.
+const (
+	EventHost     = "host"
+	EventPort     = "port"
+	EventProgress = "progress"
+	EventSummary  = "summary"
+)
+
+// HostEvent reports a host that answered host discovery.
+type HostEvent struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Address  string    `json:"address"`
+	RTT      float64   `json:"rtt_ms"`
+	Attempts int       `json:"attempts"`
+}
+
+// PortEvent reports one port's final result, open or not. Its time, when the
+// result came in, takes the place of the port's send time.
+type PortEvent struct {
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Address  string    `json:"address"`
+	Hostname string    `json:"hostname,omitempty"`
+	Port
+}
+
+// ProgressEvent is sent periodically while a port scan runs.
+type ProgressEvent struct {
+	Type      string    `json:"type"`
+	Time      time.Time `json:"time"`
+	Done      int       `json:"done"`
+	Total     int       `json:"total"`
+	Open      int       `json:"open"`
+	ElapsedMS float64   `json:"elapsed_ms"`
+	Rate      float64   `json:"probes_per_second"`
+}
+
+// SummaryEvent ends the stream with the run's metadata and statistics.
+type SummaryEvent struct {
+	Type        string    `json:"type"`
+	Time        time.Time `json:"time"`
+	Scanner     string    `json:"scanner"`
+	Version     string    `json:"version"`
+	Command     string    `json:"command"`
+	Args        []string  `json:"args"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Interrupted bool      `json:"interrupted"`
+	Error       string    `json:"error,omitempty"`
+	Stats       Stats     `json:"stats"`
+}
+
+// Stream writes scan events as JSON Lines, one object per line as they
+// happen. It is safe for concurrent use.
+type Stream struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	// err is the first write error; later events are dropped.
+	err error
+}
+
+func NewStream(w io.Writer) *Stream {
+	return &Stream{enc: json.NewEncoder(w)}
+}
+
+func (s *Stream) Host(result scan.HostResult) {
+	s.write(HostEvent{
+		Type:     EventHost,
+		Time:     time.Now(),
+		Address:  result.IP,
+		RTT:      millis(result.RTT),
+		Attempts: result.Attempts,
+	})
+}
+
+func (s *Stream) Port(result scan.PortResult) {
+	s.write(PortEvent{
+		Type:     EventPort,
+		Time:     time.Now(),
+		Address:  result.Host,
+		Hostname: result.Hostname,
+		Port:     NewPort(result),
+	})
+}
+
+// Progress reports done of total probes after elapsed, at rate probes per
+// second.
+func (s *Stream) Progress(done, total, open int, elapsed time.Duration, rate float64) {
+	s.write(ProgressEvent{
+		Type:      EventProgress,
+		Time:      time.Now(),
+		Done:      done,
+		Total:     total,
+		Open:      open,
+		ElapsedMS: millis(elapsed),
+		Rate:      rate,
+	})
+}
+
+// Summary ends the stream with r's metadata and statistics; its hosts were
+// already streamed.
+func (s *Stream) Summary(r *Report) error {
+	s.write(SummaryEvent{
+		Type:        EventSummary,
+		Time:        time.Now(),
+		Scanner:     r.Scanner,
+		Version:     r.Version,
+		Command:     r.Command,
+		Args:        r.Args,
+		Start:       r.Start,
+		End:         r.End,
+		Interrupted: r.Interrupted,
+		Error:       r.Error,
+		Stats:       r.Stats,
+	})
+	return s.Err()
+}
+
+// Err is the first error writing the stream, if any.
+func (s *Stream) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
+
+func (s *Stream) write(event any) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.err != nil {
+		return
+	}
+	if err := s.enc.Encode(event); err != nil {
+		s.err = fmt.Errorf("error writing JSON Lines event: %w", err)
+	}
+}
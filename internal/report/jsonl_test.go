@@ -0,0 +1,90 @@
+package report
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jspback/bingus/internal/scan"
+)
+
+func TestStreamWritesOneObjectPerLine(t *testing.T) {
+	var buf bytes.Buffer
+	stream := NewStream(&buf)
+
+	stream.Host(scan.HostResult{IP: "10.0.0.5", RTT: 3 * time.Millisecond, Attempts: 1})
+	stream.Port(scan.PortResult{Host: "10.0.0.5", Hostname: "db01", Port: 22, Proto: "tcp", Service: "ssh", State: scan.StateOpen, Open: true})
+	stream.Progress(10, 100, 1, 2*time.Second, 5)
+	targets, results, summary := portScan()
+	if err := stream.Summary(Ports(Meta{Command: "port"}, targets, results, summary)); err != nil {
+		t.Fatalf("Summary() error: %v", err)
+	}
+
+	var types []string
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var event map[string]any
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		types = append(types, event["type"].(string))
+
+		switch event["type"] {
+		case EventPort:
+			if event["address"] != "10.0.0.5" || event["hostname"] != "db01" || event["state"] != "open" || event["port"] != 22.0 {
+				t.Errorf("port event = %v", event)
+			}
+		case EventSummary:
+			if _, ok := event["hosts"]; ok {
+				t.Errorf("summary repeats the streamed hosts: %v", event)
+			}
+			if stats := event["stats"].(map[string]any); stats["probes"] != 12.0 {
+				t.Errorf("summary stats = %v, want 12 probes", stats)
+			}
+		}
+	}
+
+	want := []string{EventHost, EventPort, EventProgress, EventSummary}
+	if len(types) != len(want) {
+		t.Fatalf("event types = %v, want %v", types, want)
+	}
+	for i := range want {
+		if types[i] != want[i] {
+			t.Fatalf("event types = %v, want %v", types, want)
+		}
+	}
+}
+
+func TestStreamConcurrentWrites(t *testing.T) {
+	var buf bytes.Buffer
+	stream := NewStream(&buf)
+
+	var wg sync.WaitGroup
+	for i := range 50 {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			stream.Port(scan.PortResult{Host: "127.0.0.1", Port: i, Proto: "tcp"})
+		}()
+		go func() {
+			defer wg.Done()
+			stream.Progress(i, 50, 0, time.Second, float64(i))
+		}()
+	}
+	wg.Wait()
+
+	lines := 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		if !json.Valid(scanner.Bytes()) {
+			t.Fatalf("interleaved line: %q", scanner.Text())
+		}
+		lines++
+	}
+	if lines != 100 {
+		t.Errorf("wrote %d lines, want 100", lines)
+	}
+}
@@ -0,0 +1,277 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// nmapXMLVersion is the nmap XML output format version written by WriteNmapXML.
+const nmapXMLVersion = "1.05"
+
+// The nmap* types mirror the elements of nmap's DTD that bingus can fill
+// in, in the order the DTD requires.
+type nmapRun struct {
+	XMLName          xml.Name       `xml:"nmaprun"`
+	Scanner          string         `xml:"scanner,attr"`
+	Args             string         `xml:"args,attr"`
+	Start            int64          `xml:"start,attr"`
+	StartStr         string         `xml:"startstr,attr"`
+	Version          string         `xml:"version,attr"`
+	XMLOutputVersion string         `xml:"xmloutputversion,attr"`
+	ScanInfo         []nmapScanInfo `xml:"scaninfo"`
+	Verbose          nmapLevel      `xml:"verbose"`
+	Debugging        nmapLevel      `xml:"debugging"`
+	Hosts            []nmapHost     `xml:"host"`
+	RunStats         nmapRunStats   `xml:"runstats"`
+}
+
+type nmapScanInfo struct {
+	Type        string `xml:"type,attr"`
+	Protocol    string `xml:"protocol,attr"`
+	NumServices int    `xml:"numservices,attr"`
+	Services    string `xml:"services,attr"`
+}
+
+type nmapLevel struct {
+	Level int `xml:"level,attr"`
+}
+
+type nmapHost struct {
+	Status    nmapStatus     `xml:"status"`
+	Address   nmapAddress    `xml:"address"`
+	Hostnames *nmapHostnames `xml:"hostnames"`
+	Ports     *nmapPorts     `xml:"ports"`
+}
+
+type nmapStatus struct {
+	State     string `xml:"state,attr"`
+	Reason    string `xml:"reason,attr"`
+	ReasonTTL int    `xml:"reason_ttl,attr"`
+}
+
+type nmapAddress struct {
+	Addr     string `xml:"addr,attr"`
+	AddrType string `xml:"addrtype,attr"`
+}
+
+type nmapHostnames struct {
+	Hostnames []nmapHostname `xml:"hostname"`
+}
+
+type nmapHostname struct {
+	Name string `xml:"name,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type nmapPorts struct {
+	ExtraPorts []nmapExtraPorts `xml:"extraports"`
+	Ports      []nmapPort       `xml:"port"`
+}
+
+type nmapExtraPorts struct {
+	State   string            `xml:"state,attr"`
+	Count   int               `xml:"count,attr"`
+	Reasons []nmapExtraReason `xml:"extrareasons"`
+}
+
+type nmapExtraReason struct {
+	Reason string `xml:"reason,attr"`
+	Count  int    `xml:"count,attr"`
+}
+
+type nmapPort struct {
+	Protocol string       `xml:"protocol,attr"`
+	PortID   int          `xml:"portid,attr"`
+	State    nmapStatus   `xml:"state"`
+	Service  *nmapService `xml:"service"`
+}
+
+type nmapService struct {
+	Name    string `xml:"name,attr"`
+	Product string `xml:"product,attr,omitempty"`
+	Tunnel  string `xml:"tunnel,attr,omitempty"`
+	Method  string `xml:"method,attr"`
+	Conf    int    `xml:"conf,attr"`
+}
+
+type nmapRunStats struct {
+	Finished nmapFinished  `xml:"finished"`
+	Hosts    nmapHostStats `xml:"hosts"`
+}
+
+type nmapFinished struct {
+	Time     int64  `xml:"time,attr"`
+	TimeStr  string `xml:"timestr,attr"`
+	Elapsed  string `xml:"elapsed,attr"`
+	Summary  string `xml:"summary,attr"`
+	Exit     string `xml:"exit,attr"`
+	ErrorMsg string `xml:"errormsg,attr,omitempty"`
+}
+
+type nmapHostStats struct {
+	Up    int `xml:"up,attr"`
+	Down  int `xml:"down,attr"`
+	Total int `xml:"total,attr"`
+}
+
+// nmapTimeFormat is the ctime-like format of nmap's startstr and timestr.
+const nmapTimeFormat = "Mon Jan _2 15:04:05 2006"
+
+// WriteNmapXML writes the report in nmap's XML output format, so tools that
+// import nmap scans can read it. nmap's DTD only allows "nmap" as the
+// scanner, so bingus names itself in a comment and the version attribute.
+func (r *Report) WriteNmapXML(w io.Writer) error {
+	run := nmapRun{
+		Scanner:          "nmap",
+		Args:             strings.Join(append([]string{Scanner}, r.Args...), " "),
+		Start:            r.Start.Unix(),
+		StartStr:         r.Start.Format(nmapTimeFormat),
+		Version:          Scanner + " " + r.Version,
+		XMLOutputVersion: nmapXMLVersion,
+		RunStats:         r.nmapRunStats(),
+	}
+	for _, scanned := range r.Scanned {
+		scanType := "connect"
+		if scanned.Protocol == "udp" {
+			scanType = "udp"
+		}
+		run.ScanInfo = append(run.ScanInfo, nmapScanInfo{
+			Type:        scanType,
+			Protocol:    scanned.Protocol,
+			NumServices: scanned.Count,
+			Services:    scanned.Ports,
+		})
+	}
+
+	states := make(map[string]map[string]int)
+	for _, host := range r.Stats.HostTimes {
+		states[host.Address] = host.States
+	}
+	for _, host := range r.Hosts {
+		run.Hosts = append(run.Hosts, r.nmapHost(host, states[host.Address]))
+	}
+
+	if _, err := fmt.Fprintf(w, "%s<!DOCTYPE nmaprun>\n<!-- %s %s scan initiated %s as: %s -->\n",
+		xml.Header, Scanner, r.Version, run.StartStr, xmlComment(run.Args)); err != nil {
+		return fmt.Errorf("error writing nmap XML report: %w", err)
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(run); err != nil {
+		return fmt.Errorf("error writing nmap XML report: %w", err)
+	}
+	if _, err := io.WriteString(w, "\n"); err != nil {
+		return fmt.Errorf("error writing nmap XML report: %w", err)
+	}
+	return nil
+}
+
+func (r *Report) nmapHost(host Host, states map[string]int) nmapHost {
+	result := nmapHost{
+		Status:  nmapStatus{State: host.Status, Reason: "user-set"},
+		Address: nmapAddress{Addr: host.Address, AddrType: "ipv4"},
+	}
+	switch {
+	case r.Command == "ping":
+		result.Status.Reason = "echo-reply"
+	case host.Status != StatusUp:
+		result.Status.Reason = "no-response"
+	}
+	if addr, err := netip.ParseAddr(host.Address); err == nil && addr.Is6() {
+		result.Address.AddrType = "ipv6"
+	}
+	if host.Hostname != "" {
+		result.Hostnames = &nmapHostnames{Hostnames: []nmapHostname{{Name: host.Hostname, Type: "user"}}}
+	}
+	if r.Command == "ping" {
+		return result
+	}
+
+	// Other states are only counted per host, across protocols, so their
+	// reason assumes TCP unless the scan was UDP only.
+	proto := "tcp"
+	if len(r.Scanned) == 1 {
+		proto = r.Scanned[0].Protocol
+	}
+	result.Ports = &nmapPorts{}
+	for _, state := range slices.Sorted(maps.Keys(states)) {
+		if state == "open" || states[state] == 0 {
+			continue
+		}
+		result.Ports.ExtraPorts = append(result.Ports.ExtraPorts, nmapExtraPorts{
+			State:   state,
+			Count:   states[state],
+			Reasons: []nmapExtraReason{{Reason: nmapReason(state, proto), Count: states[state]}},
+		})
+	}
+	for _, port := range host.Ports {
+		nport := nmapPort{
+			Protocol: port.Protocol,
+			PortID:   port.Port,
+			State:    nmapStatus{State: port.State, Reason: nmapReason(port.State, port.Protocol)},
+		}
+		if port.Service != "" {
+			nport.Service = &nmapService{Name: port.Service, Product: port.Banner(), Method: "table", Conf: 3}
+			if port.TLS != nil && port.TLS.Error == "" && port.TLS.StartTLS == "" {
+				nport.Service.Tunnel = "ssl"
+			}
+		}
+		result.Ports.Ports = append(result.Ports.Ports, nport)
+	}
+	return result
+}
+
+func (r *Report) nmapRunStats() nmapRunStats {
+	elapsed := r.End.Sub(r.Start).Seconds()
+	stats := nmapRunStats{
+		Finished: nmapFinished{
+			Time:    r.End.Unix(),
+			TimeStr: r.End.Format(nmapTimeFormat),
+			Elapsed: strconv.FormatFloat(elapsed, 'f', 2, 64),
+			Exit:    "success",
+		},
+		Hosts: nmapHostStats{Up: r.Stats.Up, Total: len(r.Hosts)},
+	}
+	if r.Command == "ping" {
+		stats.Hosts.Total = r.Stats.Hosts
+	}
+	stats.Hosts.Down = max(stats.Hosts.Total-stats.Hosts.Up, 0)
+
+	stats.Finished.Summary = fmt.Sprintf("%s done at %s; %d IP addresses (%d hosts up) scanned in %.2f seconds",
+		Scanner, stats.Finished.TimeStr, stats.Hosts.Total, stats.Hosts.Up, elapsed)
+	switch {
+	case r.Interrupted:
+		stats.Finished.Exit, stats.Finished.ErrorMsg = "error", "scan interrupted"
+	case r.Error != "":
+		stats.Finished.Exit, stats.Finished.ErrorMsg = "error", r.Error
+	}
+	return stats
+}
+
+// nmapReason is the reason nmap gives for a port state found by a connect
+// or UDP scan.
+func nmapReason(state, proto string) string {
+	switch {
+	case state == "open" && proto == "udp":
+		return "udp-response"
+	case state == "open":
+		return "syn-ack"
+	case state == "closed" && proto == "udp":
+		return "port-unreach"
+	case state == "closed":
+		return "conn-refused"
+	default:
+		return "no-response"
+	}
+}
+
+// xmlComment keeps text from ending the comment it is written in.
+func xmlComment(s string) string {
+	return strings.ReplaceAll(s, "--", "- -")
+}
@@ -0,0 +1,173 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"io"
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/jspback/bingus/internal/probe"
+	"github.com/jspback/bingus/internal/scan"
+	"github.com/jspback/bingus/internal/util"
+)
+
+// nmapContent is the content model of the nmap DTD elements WriteNmapXML
+// writes, as a regexp over the child element names.
+var nmapContent = map[string]*regexp.Regexp{
+	"nmaprun":    regexp.MustCompile(`^(scaninfo )*verbose debugging (host )*runstats $`),
+	"host":       regexp.MustCompile(`^status address ((address|hostnames|ports) )*(times )?$`),
+	"hostnames":  regexp.MustCompile(`^(hostname )*$`),
+	"ports":      regexp.MustCompile(`^(extraports )*(port )*$`),
+	"extraports": regexp.MustCompile(`^(extrareasons )*$`),
+	"port":       regexp.MustCompile(`^state (owner )?(service )?$`),
+	"runstats":   regexp.MustCompile(`^finished hosts $`),
+}
+
+// nmapRequired lists the DTD's #REQUIRED attributes of those elements.
+var nmapRequired = map[string][]string{
+	"nmaprun":      {"scanner", "version", "xmloutputversion"},
+	"scaninfo":     {"type", "protocol", "numservices", "services"},
+	"verbose":      {"level"},
+	"debugging":    {"level"},
+	"status":       {"state", "reason", "reason_ttl"},
+	"address":      {"addr"},
+	"extraports":   {"state", "count"},
+	"extrareasons": {"reason", "count"},
+	"port":         {"protocol", "portid"},
+	"state":        {"state", "reason", "reason_ttl"},
+	"service":      {"name", "conf", "method"},
+	"finished":     {"time", "elapsed"},
+}
+
+// nmapEnums are the attributes the DTD restricts to a list of values.
+var nmapEnums = map[string][]string{
+	"nmaprun.scanner":   {"nmap"},
+	"scaninfo.type":     {"syn", "ack", "bounce", "connect", "null", "xmas", "window", "maimon", "fin", "udp", "sctpinit", "sctpcookieecho", "ipproto"},
+	"scaninfo.protocol": {"ip", "tcp", "udp", "sctp"},
+	"status.state":      {"up", "down", "unknown", "skipped"},
+	"address.addrtype":  {"ipv4", "ipv6", "mac"},
+	"hostname.type":     {"user", "PTR"},
+	"port.protocol":     {"ip", "tcp", "udp", "sctp"},
+	"service.method":    {"table", "probed"},
+	"service.tunnel":    {"ssl"},
+	"finished.exit":     {"error", "success"},
+}
+
+// checkNmapXML checks data against the parts of nmap's DTD that apply.
+func checkNmapXML(t *testing.T, data []byte) {
+	t.Helper()
+
+	if !bytes.Contains(data, []byte("<!DOCTYPE nmaprun>")) {
+		t.Error("no nmaprun doctype")
+	}
+
+	type open struct {
+		name     string
+		children string
+	}
+	var stack []open
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("invalid XML: %v", err)
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			name := token.Name.Local
+			if len(stack) > 0 {
+				stack[len(stack)-1].children += name + " "
+			}
+			stack = append(stack, open{name: name})
+
+			attrs := make(map[string]string)
+			for _, attr := range token.Attr {
+				attrs[attr.Name.Local] = attr.Value
+			}
+			for _, required := range nmapRequired[name] {
+				if _, ok := attrs[required]; !ok {
+					t.Errorf("<%s> lacks required attribute %s", name, required)
+				}
+			}
+			for attr, value := range attrs {
+				if allowed, ok := nmapEnums[name+"."+attr]; ok && !slices.Contains(allowed, value) {
+					t.Errorf("<%s %s=%q> is not one of %v", name, attr, value, allowed)
+				}
+			}
+		case xml.EndElement:
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			if model, ok := nmapContent[top.name]; ok && !model.MatchString(top.children) {
+				t.Errorf("<%s> has children %q, not allowed by %s", top.name, top.children, model)
+			}
+		}
+	}
+}
+
+func TestWriteNmapXML(t *testing.T) {
+	targets, results, summary := portScan()
+	results["10.0.0.5"][0].TLS = &probe.TLSInfo{Version: "TLS 1.3"}
+	summary.Stats.Hosts[0].States = map[string]int{"closed": 2, "filtered": 1}
+	meta := Meta{
+		Version: "v1.0.0",
+		Command: "port",
+		Args:    []string{"port", "--hosts", "db01"},
+		Ports:   []util.Port{{Number: 22, Proto: "tcp"}, {Number: 5432, Proto: "tcp"}, {Number: 23, Proto: "tcp"}, {Number: 53, Proto: "udp"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Ports(meta, targets, results, summary).WriteNmapXML(&buf); err != nil {
+		t.Fatalf("WriteNmapXML() error: %v", err)
+	}
+	checkNmapXML(t, buf.Bytes())
+
+	out := buf.String()
+	for _, want := range []string{
+		`<scaninfo type="connect" protocol="tcp" numservices="3" services="22-23,5432">`,
+		`<scaninfo type="udp" protocol="udp" numservices="1" services="53">`,
+		`<address addr="2001:db8::1" addrtype="ipv6">`,
+		`<hostname name="db01" type="user">`,
+		`<extraports state="closed" count="2">`,
+		`<port protocol="tcp" portid="5432">`,
+		`<service name="postgresql" tunnel="ssl" method="table" conf="3">`,
+		`<state state="open" reason="udp-response" reason_ttl="0">`,
+		`<status state="unknown" reason="no-response" reason_ttl="0">`,
+		`<hosts up="2" down="2" total="4">`,
+		`exit="success"`,
+		`<!-- bingus v1.0.0 scan initiated`,
+		`as: bingus port - -hosts db01 -->`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("XML lacks %s:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteNmapXMLHostDiscovery(t *testing.T) {
+	r := Hosts(Meta{Version: "v1.0.0", Command: "ping"}, []scan.HostResult{{IP: "192.168.1.7"}},
+		scan.Summary{Hosts: 254, Up: 1, Start: start, End: end, Err: context.Canceled})
+
+	var buf bytes.Buffer
+	if err := r.WriteNmapXML(&buf); err != nil {
+		t.Fatalf("WriteNmapXML() error: %v", err)
+	}
+	checkNmapXML(t, buf.Bytes())
+
+	out := buf.String()
+	for _, want := range []string{`reason="echo-reply"`, `<hosts up="1" down="253" total="254">`, `exit="error" errormsg="scan interrupted"`} {
+		if !strings.Contains(out, want) {
+			t.Errorf("XML lacks %s:\n%s", want, out)
+		}
+	}
+	if strings.Contains(out, "<ports>") {
+		t.Errorf("host discovery XML has ports:\n%s", out)
+	}
+}
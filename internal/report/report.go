@@ -0,0 +1,428 @@
+// Package report turns scan results into the machine-readable documents the
+// CLI writes with --output. The types here are the stable schema of those
+// documents, kept apart from the scan package's internals so that changing
+// a scanner struct does not change the output.
+package report
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"net/netip"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jspback/bingus/internal/probe"
+	"github.com/jspback/bingus/internal/scan"
+	"github.com/jspback/bingus/internal/util"
+)
+
+// Scanner names the program in report metadata.
+const Scanner = "bingus"
+
+// Host statuses. A port scan does not ping, so a target that answered no
+// probe is unknown rather than down.
+const (
+	StatusUp      = "up"
+	StatusUnknown = "unknown"
+)
+
+// Meta describes the run that produced a report.
+type Meta struct {
+	Version string
+	// Command is "ping" or "port".
+	Command string
+	Args    []string
+	// Ports are the ports a port scan probed on every host.
+	Ports []util.Port
+}
+
+// Report is one scan's results. Hosts are sorted by address and each host's
+// ports by protocol and number, so equal scans produce equal documents.
+type Report struct {
+	Scanner     string    `json:"scanner"`
+	Version     string    `json:"version"`
+	Command     string    `json:"command"`
+	Args        []string  `json:"args"`
+	Start       time.Time `json:"start"`
+	End         time.Time `json:"end"`
+	Interrupted bool      `json:"interrupted"`
+	Error       string    `json:"error,omitempty"`
+	Scanned     []Scanned `json:"scanned,omitempty"`
+	Hosts       []Host    `json:"hosts"`
+	Stats       Stats     `json:"stats"`
+}
+
+// Scanned lists the ports of one protocol that a port scan probed, in the
+// compact form of a port spec, such as "1-1024,8080".
+type Scanned struct {
+	Protocol string `json:"protocol"`
+	Count    int    `json:"count"`
+	Ports    string `json:"ports"`
+}
+
+type Host struct {
+	Address  string   `json:"address"`
+	Hostname string   `json:"hostname,omitempty"`
+	Status   string   `json:"status"`
+	RTT      *float64 `json:"rtt_ms,omitempty"`
+	Attempts int      `json:"attempts,omitempty"`
+	// Ports lists the open ports found; the other states are only counted
+	// in Stats.
+	Ports []Port `json:"ports"`
+}
+
+type Port struct {
+	Port     int       `json:"port"`
+	Protocol string    `json:"protocol"`
+	State    string    `json:"state"`
+	Service  string    `json:"service,omitempty"`
+	RTT      float64   `json:"rtt_ms"`
+	Attempts int       `json:"attempts"`
+	Time     time.Time `json:"time"`
+	// Error is the kind of error that decided a port's state; see
+	// scan.ErrorKind.
+	Error string `json:"error,omitempty"`
+	TLS   *TLS   `json:"tls,omitempty"`
+	HTTP  *HTTP  `json:"http,omitempty"`
+	SSH   *SSH   `json:"ssh,omitempty"`
+}
+
+type TLS struct {
+	StartTLS    string       `json:"starttls,omitempty"`
+	Offered     bool         `json:"offered"`
+	Version     string       `json:"version,omitempty"`
+	CipherSuite string       `json:"cipher_suite,omitempty"`
+	Certificate *Certificate `json:"certificate,omitempty"`
+	Error       string       `json:"error,omitempty"`
+}
+
+type Certificate struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	DNSNames  []string  `json:"dns_names,omitempty"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+	SHA256    string    `json:"sha256"`
+}
+
+type HTTP struct {
+	URL           string   `json:"url"`
+	StatusCode    int      `json:"status_code"`
+	Server        string   `json:"server,omitempty"`
+	PoweredBy     string   `json:"powered_by,omitempty"`
+	Title         string   `json:"title,omitempty"`
+	Redirects     []string `json:"redirects,omitempty"`
+	ContentLength int64    `json:"content_length"`
+	FaviconHash   string   `json:"favicon_hash,omitempty"`
+}
+
+type SSH struct {
+	Banner            string       `json:"banner"`
+	KexAlgorithms     []string     `json:"kex_algorithms"`
+	HostKeyAlgorithms []string     `json:"host_key_algorithms"`
+	Ciphers           []string     `json:"ciphers"`
+	MACs              []string     `json:"macs"`
+	HostKeys          []SSHHostKey `json:"host_keys,omitempty"`
+	HostKeyError      string       `json:"host_key_error,omitempty"`
+	Weak              []string     `json:"weak,omitempty"`
+}
+
+type SSHHostKey struct {
+	Type        string `json:"type"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+type Stats struct {
+	Probes    int            `json:"probes"`
+	Hosts     int            `json:"hosts"`
+	Up        int            `json:"up"`
+	Open      int            `json:"open"`
+	ElapsedMS float64        `json:"elapsed_ms"`
+	Rate      float64        `json:"probes_per_second"`
+	States    map[string]int `json:"states"`
+	Errors    map[string]int `json:"errors"`
+	RTT       RTTStats       `json:"rtt_ms"`
+	HostTimes []HostTime     `json:"host_durations,omitempty"`
+}
+
+type RTTStats struct {
+	Samples int     `json:"samples"`
+	Min     float64 `json:"min"`
+	P50     float64 `json:"p50"`
+	P90     float64 `json:"p90"`
+	P99     float64 `json:"p99"`
+	Max     float64 `json:"max"`
+}
+
+type HostTime struct {
+	Address    string         `json:"address"`
+	Hostname   string         `json:"hostname,omitempty"`
+	Probes     int            `json:"probes"`
+	Answered   int            `json:"answered"`
+	Open       int            `json:"open"`
+	States     map[string]int `json:"states"`
+	DurationMS float64        `json:"duration_ms"`
+}
+
+// Ports builds the report of a port scan of targets, with results as
+// returned by scan.PortDiscovery. Closed and filtered results added to them
+// are listed too, which only WriteCSV is meant for: the other formats count
+// those states per host instead.
+func Ports(meta Meta, targets []scan.Target, results map[string][]scan.PortResult, summary scan.Summary) *Report {
+	r := newReport(meta, summary)
+
+	answered := make(map[string]scan.HostStats)
+	for _, host := range summary.Stats.Hosts {
+		answered[host.Address] = host
+	}
+
+	for _, target := range targets {
+		host := Host{Address: target.Address, Hostname: target.Hostname, Status: StatusUnknown, Ports: []Port{}}
+		for _, result := range results[target.Address] {
+			host.Ports = append(host.Ports, NewPort(result))
+		}
+		slices.SortFunc(host.Ports, comparePorts)
+
+		if stats, ok := answered[target.Address]; ok && stats.SRTT > 0 {
+			rtt := millis(stats.SRTT)
+			host.RTT = &rtt
+		}
+		// Open ports restored from a checkpoint count, though this run's
+		// stats do not cover them.
+		if slices.ContainsFunc(host.Ports, Port.open) || answered[target.Address].Answered > 0 {
+			host.Status = StatusUp
+			r.Stats.Up++
+		}
+		r.Hosts = append(r.Hosts, host)
+	}
+	slices.SortStableFunc(r.Hosts, compareHosts)
+	return r
+}
+
+func (p Port) open() bool {
+	return p.State == scan.StateOpen.String()
+}
+
+// Hosts builds the report of a host discovery scan.
+func Hosts(meta Meta, hosts []scan.HostResult, summary scan.Summary) *Report {
+	r := newReport(meta, summary)
+	for _, result := range hosts {
+		rtt := millis(result.RTT)
+		r.Hosts = append(r.Hosts, Host{
+			Address:  result.IP,
+			Status:   StatusUp,
+			RTT:      &rtt,
+			Attempts: result.Attempts,
+			Ports:    []Port{},
+		})
+	}
+	slices.SortStableFunc(r.Hosts, compareHosts)
+	return r
+}
+
+func newReport(meta Meta, summary scan.Summary) *Report {
+	r := &Report{
+		Scanner:     Scanner,
+		Version:     meta.Version,
+		Command:     meta.Command,
+		Args:        meta.Args,
+		Start:       summary.Start,
+		End:         summary.End,
+		Interrupted: errors.Is(summary.Err, context.Canceled),
+		Hosts:       []Host{},
+		Stats:       NewStats(summary),
+	}
+	if r.Args == nil {
+		r.Args = []string{}
+	}
+	r.Scanned = scanned(meta.Ports)
+	if summary.Err != nil && !r.Interrupted {
+		r.Error = summary.Err.Error()
+	}
+	return r
+}
+
+// NewStats converts a scan summary's counts and statistics.
+func NewStats(summary scan.Summary) Stats {
+	stats := Stats{
+		Probes:    summary.Probes,
+		Hosts:     summary.Hosts,
+		Up:        summary.Up,
+		Open:      summary.Open,
+		ElapsedMS: millis(summary.End.Sub(summary.Start)),
+		Rate:      summary.Rate(),
+		States:    summary.Stats.States,
+		Errors:    summary.Stats.Errors,
+		RTT: RTTStats{
+			Samples: summary.Stats.RTT.Samples,
+			Min:     millis(summary.Stats.RTT.Min),
+			P50:     millis(summary.Stats.RTT.P50),
+			P90:     millis(summary.Stats.RTT.P90),
+			P99:     millis(summary.Stats.RTT.P99),
+			Max:     millis(summary.Stats.RTT.Max),
+		},
+	}
+	if stats.States == nil {
+		stats.States = map[string]int{}
+	}
+	if stats.Errors == nil {
+		stats.Errors = map[string]int{}
+	}
+	for _, host := range summary.Stats.Hosts {
+		stats.HostTimes = append(stats.HostTimes, HostTime{
+			Address:    host.Address,
+			Hostname:   host.Hostname,
+			Probes:     host.Probes,
+			Answered:   host.Answered,
+			Open:       host.Open,
+			States:     host.States,
+			DurationMS: millis(host.Duration),
+		})
+	}
+	return stats
+}
+
+// NewPort converts one port result.
+func NewPort(result scan.PortResult) Port {
+	return Port{
+		Port:     result.Port,
+		Protocol: result.Proto,
+		State:    result.State.String(),
+		Service:  result.Service,
+		RTT:      millis(result.RTT),
+		Attempts: result.Attempts,
+		Time:     result.Time,
+		Error:    scan.ErrorKind(result.Error),
+		TLS:      newTLS(result.TLS),
+		HTTP:     newHTTP(result.HTTP),
+		SSH:      newSSH(result.SSH),
+	}
+}
+
+func newTLS(info *probe.TLSInfo) *TLS {
+	if info == nil {
+		return nil
+	}
+	tls := &TLS{
+		Offered:     info.Offered || !info.StartTLS,
+		Version:     info.Version,
+		CipherSuite: info.CipherSuite,
+		Error:       info.Error,
+	}
+	if info.StartTLS {
+		tls.StartTLS = info.Protocol
+	}
+	if cert := info.Certificate; cert != nil {
+		tls.Certificate = &Certificate{
+			Subject:   cert.Subject,
+			Issuer:    cert.Issuer,
+			DNSNames:  cert.DNSNames,
+			NotBefore: cert.NotBefore,
+			NotAfter:  cert.NotAfter,
+			SHA256:    cert.SHA256,
+		}
+	}
+	return tls
+}
+
+func newHTTP(info *probe.HTTPInfo) *HTTP {
+	if info == nil {
+		return nil
+	}
+	return &HTTP{
+		URL:           info.URL,
+		StatusCode:    info.StatusCode,
+		Server:        info.Server,
+		PoweredBy:     info.PoweredBy,
+		Title:         info.Title,
+		Redirects:     info.Redirects,
+		ContentLength: info.ContentLength,
+		FaviconHash:   info.FaviconHash,
+	}
+}
+
+func newSSH(info *probe.SSHInfo) *SSH {
+	if info == nil {
+		return nil
+	}
+	ssh := &SSH{
+		Banner:            info.Banner,
+		KexAlgorithms:     info.KexAlgorithms,
+		HostKeyAlgorithms: info.HostKeyAlgorithms,
+		Ciphers:           info.Ciphers,
+		MACs:              info.MACs,
+		HostKeyError:      info.HostKeyError,
+		Weak:              info.Weak,
+	}
+	for _, key := range info.HostKeys {
+		ssh.HostKeys = append(ssh.HostKeys, SSHHostKey{Type: key.Type, Fingerprint: key.Fingerprint})
+	}
+	return ssh
+}
+
+// scanned groups ports by protocol, TCP first, and compresses consecutive
+// numbers into ranges.
+func scanned(ports []util.Port) []Scanned {
+	byProto := make(map[string][]int)
+	for _, port := range ports {
+		byProto[port.Proto] = append(byProto[port.Proto], port.Number)
+	}
+
+	var result []Scanned
+	for _, proto := range slices.Sorted(maps.Keys(byProto)) {
+		numbers := byProto[proto]
+		slices.Sort(numbers)
+		numbers = slices.Compact(numbers)
+
+		var ranges []string
+		for i := 0; i < len(numbers); {
+			j := i
+			for j+1 < len(numbers) && numbers[j+1] == numbers[j]+1 {
+				j++
+			}
+			if i == j {
+				ranges = append(ranges, strconv.Itoa(numbers[i]))
+			} else {
+				ranges = append(ranges, fmt.Sprintf("%d-%d", numbers[i], numbers[j]))
+			}
+			i = j + 1
+		}
+		result = append(result, Scanned{Protocol: proto, Count: len(numbers), Ports: strings.Join(ranges, ",")})
+	}
+	return result
+}
+
+// compareHosts orders IP addresses numerically, IPv4 first, and puts
+// unresolved hostnames after them.
+func compareHosts(a, b Host) int {
+	addrA, errA := netip.ParseAddr(a.Address)
+	addrB, errB := netip.ParseAddr(b.Address)
+	switch {
+	case errA == nil && errB == nil:
+		return addrA.Compare(addrB)
+	case errA == nil:
+		return -1
+	case errB == nil:
+		return 1
+	default:
+		return cmp.Compare(a.Address, b.Address)
+	}
+}
+
+// comparePorts puts TCP before UDP, then orders by number.
+func comparePorts(a, b Port) int {
+	if a.Protocol != b.Protocol {
+		return cmp.Compare(a.Protocol, b.Protocol)
+	}
+	return cmp.Compare(a.Port, b.Port)
+}
+
+// millis converts d to milliseconds, keeping microsecond precision.
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
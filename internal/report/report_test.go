@@ -0,0 +1,128 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jspback/bingus/internal/scan"
+)
+
+var (
+	start = time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	end   = start.Add(1500 * time.Millisecond)
+)
+
+func portScan() ([]scan.Target, map[string][]scan.PortResult, scan.Summary) {
+	targets := []scan.Target{
+		{Hostname: "web", Address: "10.0.0.10"},
+		{Address: "2001:db8::1"},
+		{Hostname: "db01", Address: "10.0.0.5"},
+		{Address: "10.0.0.7"},
+	}
+	results := map[string][]scan.PortResult{
+		"10.0.0.5": {
+			{Host: "10.0.0.5", Hostname: "db01", Port: 5432, Proto: "tcp", Service: "postgresql", State: scan.StateOpen, Open: true, RTT: 2 * time.Millisecond, Attempts: 1, Time: start},
+			{Host: "10.0.0.5", Hostname: "db01", Port: 53, Proto: "udp", Service: "domain", State: scan.StateOpen, Open: true, Attempts: 1, Time: start},
+			{Host: "10.0.0.5", Hostname: "db01", Port: 22, Proto: "tcp", Service: "ssh", State: scan.StateOpen, Open: true, RTT: 1500 * time.Microsecond, Attempts: 2, Time: start},
+		},
+		"10.0.0.10":   {},
+		"2001:db8::1": {},
+		"10.0.0.7":    {},
+	}
+	summary := scan.Summary{
+		Hosts: 4, Probes: 12, Open: 3, Start: start, End: end,
+		Stats: scan.Stats{
+			States: map[string]int{"open": 3, "closed": 2, "filtered": 7},
+			Errors: map[string]int{"refused": 2, "timeout": 7},
+			Hosts: []scan.HostStats{
+				{Address: "10.0.0.10", Hostname: "web", Probes: 3, Answered: 2, Duration: time.Second, SRTT: time.Millisecond},
+				{Address: "10.0.0.7", Probes: 3, Duration: time.Second},
+			},
+		},
+	}
+	return targets, results, summary
+}
+
+func TestPortsOrdersHostsAndPorts(t *testing.T) {
+	targets, results, summary := portScan()
+	r := Ports(Meta{Version: "v1.0.0", Command: "port", Args: []string{"port", "-H", "db01"}}, targets, results, summary)
+
+	var addresses []string
+	for _, host := range r.Hosts {
+		addresses = append(addresses, host.Address)
+	}
+	want := []string{"10.0.0.5", "10.0.0.7", "10.0.0.10", "2001:db8::1"}
+	if len(addresses) != len(want) {
+		t.Fatalf("hosts = %v, want %v", addresses, want)
+	}
+	for i := range want {
+		if addresses[i] != want[i] {
+			t.Fatalf("hosts = %v, want %v", addresses, want)
+		}
+	}
+
+	db := r.Hosts[0]
+	if db.Hostname != "db01" || db.Status != StatusUp || len(db.Ports) != 3 {
+		t.Fatalf("db01 = %+v, want up with 3 ports", db)
+	}
+	if db.Ports[0].Port != 22 || db.Ports[1].Port != 5432 || db.Ports[2].Protocol != "udp" {
+		t.Errorf("db01 ports = %+v, want 22/tcp, 5432/tcp, 53/udp", db.Ports)
+	}
+	if db.Ports[0].RTT != 1.5 {
+		t.Errorf("22/tcp RTT = %v ms, want 1.5", db.Ports[0].RTT)
+	}
+
+	// 10.0.0.7 never answered; web answered with refusals only.
+	if r.Hosts[1].Status != StatusUnknown || r.Hosts[2].Status != StatusUp {
+		t.Errorf("statuses = %s, %s, want unknown, up", r.Hosts[1].Status, r.Hosts[2].Status)
+	}
+	if r.Hosts[2].RTT == nil || *r.Hosts[2].RTT != 1 {
+		t.Errorf("web RTT = %v, want 1 ms", r.Hosts[2].RTT)
+	}
+	if r.Stats.Up != 2 || r.Stats.ElapsedMS != 1500 || r.Stats.Rate != 8 {
+		t.Errorf("stats = %+v, want 2 up, 1500 ms, 8 probes/s", r.Stats)
+	}
+}
+
+func TestWriteJSONIsDeterministic(t *testing.T) {
+	targets, results, summary := portScan()
+	meta := Meta{Version: "v1.0.0", Command: "port"}
+
+	var first, second bytes.Buffer
+	if err := Ports(meta, targets, results, summary).WriteJSON(&first); err != nil {
+		t.Fatalf("WriteJSON() error: %v", err)
+	}
+	// Result order from the scan must not matter.
+	results["10.0.0.5"][0], results["10.0.0.5"][2] = results["10.0.0.5"][2], results["10.0.0.5"][0]
+	targets[0], targets[3] = targets[3], targets[0]
+	if err := Ports(meta, targets, results, summary).WriteJSON(&second); err != nil {
+		t.Fatalf("WriteJSON() error: %v", err)
+	}
+	if first.String() != second.String() {
+		t.Errorf("reports differ:\n%s\n%s", first.String(), second.String())
+	}
+
+	var doc map[string]any
+	if err := json.Unmarshal(first.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid JSON: %v", err)
+	}
+	for _, key := range []string{"scanner", "version", "command", "args", "start", "end", "interrupted", "hosts", "stats"} {
+		if _, ok := doc[key]; !ok {
+			t.Errorf("report has no %q", key)
+		}
+	}
+}
+
+func TestInterruptedReport(t *testing.T) {
+	r := Hosts(Meta{Command: "ping"}, []scan.HostResult{{IP: "192.168.1.20", RTT: time.Millisecond, Attempts: 1}, {IP: "192.168.1.3"}},
+		scan.Summary{Start: start, End: end, Err: context.Canceled})
+	if !r.Interrupted || r.Error != "" {
+		t.Errorf("interrupted = %v, error = %q, want interrupted without an error", r.Interrupted, r.Error)
+	}
+	if r.Hosts[0].Address != "192.168.1.3" || r.Hosts[1].Status != StatusUp {
+		t.Errorf("hosts = %+v, want sorted and up", r.Hosts)
+	}
+}
@@ -0,0 +1,150 @@
+package scan
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+)
+
+const checkpointVersion = 1
+
+// checkpointInterval is how often a running scan saves its checkpoint.
+const checkpointInterval = 10 * time.Second
+
+// Checkpoint records the progress of a port scan so an interrupted run can be
+// continued without probing finished work again. Probes are identified by
+// their index in the host-major host×port space, so Targets and Ports are
+// stored as scanned rather than re-resolved on resume.
+type Checkpoint struct {
+	mu   sync.Mutex
+	path string
+
+	Version int          `json:"version"`
+	Args    []string     `json:"args"`
+	Seed    int64        `json:"seed"`
+	Targets []Target     `json:"targets"`
+	Ports   []util.Port  `json:"ports"`
+	Done    []byte       `json:"done"`
+	Results []PortResult `json:"results"`
+	Saved   time.Time    `json:"saved"`
+}
+
+// NewCheckpoint starts an empty checkpoint that will be saved to path. args
+// are the scan's command-line flags, kept so a resume runs with the same
+// options.
+func NewCheckpoint(path string, args []string, seed int64, targets []Target, ports []util.Port) *Checkpoint {
+	total := uint64(len(targets)) * uint64(len(ports))
+	return &Checkpoint{
+		path:    path,
+		Version: checkpointVersion,
+		Args:    args,
+		Seed:    seed,
+		Targets: targets,
+		Ports:   ports,
+		Done:    make([]byte, (total+7)/8),
+	}
+}
+
+// LoadCheckpoint reads a checkpoint saved by an earlier scan. Later saves go
+// back to the same path.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading checkpoint: %w", err)
+	}
+
+	var c Checkpoint
+	if err := json.Unmarshal(data, &c); err != nil {
+		return nil, fmt.Errorf("invalid checkpoint %s: %w", path, err)
+	}
+	if c.Version != checkpointVersion {
+		return nil, fmt.Errorf("checkpoint %s has version %d, want %d", path, c.Version, checkpointVersion)
+	}
+	total := uint64(len(c.Targets)) * uint64(len(c.Ports))
+	if uint64(len(c.Done)) != (total+7)/8 {
+		return nil, fmt.Errorf("checkpoint %s is corrupt: progress covers %d probes, want %d", path, len(c.Done)*8, total)
+	}
+
+	c.path = path
+	return &c, nil
+}
+
+// Completed counts the probes already finished.
+func (c *Checkpoint) Completed() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	completed := 0
+	for _, b := range c.Done {
+		for ; b != 0; b &= b - 1 {
+			completed++
+		}
+	}
+	return completed
+}
+
+func (c *Checkpoint) done(index uint64) bool {
+	if c == nil {
+		return false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.Done[index/8]&(1<<(index%8)) != 0
+}
+
+// record marks a probe finished, keeping its result if the port is open.
+func (c *Checkpoint) record(index uint64, result PortResult) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.Done[index/8] |= 1 << (index % 8)
+	if result.Open {
+		c.Results = append(c.Results, result)
+	}
+}
+
+// Save writes the checkpoint atomically, so a crash mid-save leaves the
+// previous checkpoint intact.
+func (c *Checkpoint) Save() error {
+	if c == nil {
+		return nil
+	}
+	c.mu.Lock()
+	c.Saved = time.Now()
+	data, err := json.Marshal(c)
+	c.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("error encoding checkpoint: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("error saving checkpoint: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("error saving checkpoint: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("error saving checkpoint: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("error saving checkpoint: %w", err)
+	}
+	return nil
+}
+
+// Path is where the checkpoint is saved.
+func (c *Checkpoint) Path() string {
+	return c.path
+}
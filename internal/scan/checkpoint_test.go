@@ -0,0 +1,98 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+)
+
+func TestCheckpointSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "scan.json")
+	targets := []Target{{Hostname: "db01", Address: "10.0.0.5"}}
+	ports := []util.Port{{Number: 22, Proto: "tcp"}, {Number: 53, Proto: "udp"}}
+
+	c := NewCheckpoint(path, []string{"--hosts=db01"}, 42, targets, ports)
+	c.record(1, PortResult{Host: "10.0.0.5", Hostname: "db01", Port: 53, Proto: "udp", State: StateOpen, Open: true})
+	if err := c.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	loaded, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error: %v", err)
+	}
+	if loaded.done(0) || !loaded.done(1) || loaded.Completed() != 1 {
+		t.Errorf("loaded progress = %08b, want only probe 1 done", loaded.Done)
+	}
+	if loaded.Seed != 42 || len(loaded.Args) != 1 || loaded.Targets[0] != targets[0] || loaded.Ports[1] != ports[1] {
+		t.Errorf("loaded checkpoint = %+v", loaded)
+	}
+	if len(loaded.Results) != 1 || loaded.Results[0].Port != 53 || loaded.Results[0].State != StateOpen {
+		t.Errorf("loaded results = %+v", loaded.Results)
+	}
+}
+
+func TestPortDiscoverySkipsCheckpointedProbes(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	openPort := listener.Addr().(*net.TCPAddr).Port
+
+	targets := []Target{{Address: "127.0.0.1"}}
+	ports := []util.Port{{Number: 1, Proto: "tcp"}, {Number: openPort, Proto: "tcp"}, {Number: 2, Proto: "tcp"}}
+	path := filepath.Join(t.TempDir(), "scan.json")
+
+	// An earlier run finished probe 0 and found port 9999 open.
+	c := NewCheckpoint(path, nil, 0, targets, ports)
+	c.record(0, PortResult{Host: "127.0.0.1", Port: 9999, Proto: "tcp", State: StateOpen, Open: true})
+
+	var found []PortResult
+	opts := PortOptions{Timeout: time.Second, Checkpoint: c}
+	results, err := PortDiscovery(context.Background(), targets, ports, opts, collect(&found, nil))
+	if err != nil {
+		t.Fatalf("PortDiscovery() error: %v", err)
+	}
+
+	if len(found) != 2 {
+		t.Errorf("probed %d ports, want the 2 not in the checkpoint", len(found))
+	}
+	for _, result := range found {
+		if result.Port == 1 {
+			t.Error("probed port 1 again")
+		}
+	}
+	if open := results["127.0.0.1"]; len(open) != 2 {
+		t.Errorf("results = %+v, want the checkpointed and the new open port", open)
+	}
+
+	saved, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error: %v", err)
+	}
+	if saved.Completed() != len(ports) {
+		t.Errorf("saved checkpoint has %d of %d probes done", saved.Completed(), len(ports))
+	}
+}
+
+func TestPortDiscoveryRejectsMismatchedCheckpoint(t *testing.T) {
+	c := NewCheckpoint(filepath.Join(t.TempDir(), "scan.json"), nil, 0, []Target{{Address: "127.0.0.1"}}, []util.Port{{Number: 1, Proto: "tcp"}})
+	ports := []util.Port{{Number: 1, Proto: "tcp"}, {Number: 2, Proto: "tcp"}}
+	if _, err := PortDiscovery(context.Background(), []Target{{Address: "127.0.0.1"}}, ports, PortOptions{Checkpoint: c}, nil); err == nil {
+		t.Error("PortDiscovery() accepted a checkpoint for a different scan")
+	}
+}
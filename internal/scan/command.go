@@ -0,0 +1,462 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/jspback/bingus/internal/probe"
+	"github.com/jspback/bingus/internal/services"
+	"github.com/jspback/bingus/internal/util"
+	"golang.org/x/net/proxy"
+)
+
+// transport is how probes reach their targets: from which local address,
+// and through which proxy if any.
+type transport struct {
+	source Source
+	proxy  proxy.ContextDialer
+}
+
+func (t transport) dial(ctx context.Context, proto, address string, timeout time.Duration) (net.Conn, error) {
+	if t.proxy != nil {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return t.proxy.DialContext(ctx, proto, address)
+	}
+	return t.source.dialer(proto, timeout).DialContext(ctx, proto, address)
+}
+
+// dialState tells what a failed connect says about the port. Refusals by the
+// target, direct or reported by a proxy, mean closed; anything else, including
+// failing to reach the proxy, means filtered.
+func dialState(err error) State {
+	var unreachable *proxyUnreachableError
+	switch {
+	case errors.As(err, &unreachable):
+		return StateFiltered
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, errProxyRefused):
+		return StateClosed
+	default:
+		return StateFiltered
+	}
+}
+
+func scanPort(ctx context.Context, host string, port util.Port, timeout time.Duration, via transport) PortResult {
+	if port.Proto == "udp" {
+		return scanUDPPort(ctx, host, port.Number, timeout, via)
+	}
+	return scanTCPPort(ctx, host, port.Number, timeout, via)
+}
+
+func scanTCPPort(ctx context.Context, host string, port int, timeout time.Duration, via transport) PortResult {
+	logger := util.NewVerboseLogger(ctx)
+	logger.Print("Scanning port %d on host %s (timeout: %v)...\n", port, host, timeout)
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	result := PortResult{Host: host, Port: port, Proto: "tcp", Service: services.Name(port, "tcp")}
+
+	startTime := time.Now()
+	conn, err := via.dial(ctx, "tcp", address, timeout)
+
+	if err != nil {
+		logger.Print("Port %d on host %s is closed: %v (in %v)\n", port, host, err, time.Since(startTime))
+		result.Error = err
+		result.State = dialState(err)
+		if result.State == StateClosed {
+			result.RTT = time.Since(startTime)
+		}
+		return result
+	}
+
+	result.RTT = time.Since(startTime)
+	logger.Print("Port %d on host %s is OPEN (connected in %v)\n", port, host, result.RTT)
+	defer conn.Close()
+	result.State = StateOpen
+	result.Open = true
+	return result
+}
+
+// scanUDPPort sends an empty datagram and waits for a reply. A reply means
+// the port is open, an ICMP port unreachable surfaces as a refused read and
+// means it is closed, and silence leaves it open|filtered.
+func scanUDPPort(ctx context.Context, host string, port int, timeout time.Duration, via transport) PortResult {
+	logger := util.NewVerboseLogger(ctx)
+	logger.Print("Scanning UDP port %d on host %s (timeout: %v)...\n", port, host, timeout)
+
+	address := net.JoinHostPort(host, strconv.Itoa(port))
+	result := PortResult{Host: host, Port: port, Proto: "udp", Service: services.Name(port, "udp")}
+
+	conn, err := via.dial(ctx, "udp", address, timeout)
+	if err != nil {
+		result.Error = err
+		result.State = StateFiltered
+		return result
+	}
+	defer conn.Close()
+
+	startTime := time.Now()
+	conn.SetDeadline(startTime.Add(timeout))
+	if _, err := conn.Write(nil); err != nil {
+		result.Error = err
+		result.State = StateFiltered
+		return result
+	}
+
+	buf := make([]byte, 1)
+	_, err = conn.Read(buf)
+	switch {
+	case err == nil:
+		result.RTT = time.Since(startTime)
+		logger.Print("UDP port %d on host %s is OPEN (reply in %v)\n", port, host, result.RTT)
+		result.State = StateOpen
+		result.Open = true
+	case errors.Is(err, syscall.ECONNREFUSED):
+		result.RTT = time.Since(startTime)
+		result.Error = err
+		result.State = StateClosed
+	default:
+		result.Error = err
+		result.State = StateOpenFiltered
+	}
+	return result
+}
+
+func inspectPort(ctx context.Context, result *PortResult, timeout time.Duration, limiter *util.RateLimiter) {
+	logger := util.NewVerboseLogger(ctx)
+
+	if probe.SupportsTLS(result.Port) && limiter.Wait(ctx) == nil {
+		info, err := probe.TLS(ctx, result.Host, result.Port, timeout)
+		if err != nil {
+			logger.Print("TLS probe of port %d on host %s failed: %v\n", result.Port, result.Host, err)
+		} else {
+			result.TLS = info
+		}
+	}
+
+	if probe.SupportsHTTP(result.Port) && limiter.Wait(ctx) == nil {
+		info, err := probe.HTTP(ctx, result.Host, result.Port, timeout)
+		if err != nil {
+			logger.Print("HTTP probe of port %d on host %s failed: %v\n", result.Port, result.Host, err)
+			result.HTTPError = err
+		} else {
+			result.HTTP = info
+		}
+	}
+
+	// Anything that is not a known TLS or web port may be an SSH server; the
+	// probe gives up quickly when the service does not send an SSH banner.
+	if !probe.SupportsTLS(result.Port) && !probe.SupportsHTTP(result.Port) && limiter.Wait(ctx) == nil {
+		info, err := probe.SSH(ctx, result.Host, result.Port, timeout)
+		if errors.Is(err, probe.ErrNotSSH) {
+			return
+		}
+		if err != nil {
+			logger.Print("SSH probe of port %d on host %s failed: %v\n", result.Port, result.Host, err)
+		} else {
+			result.SSH = info
+		}
+	}
+}
+
+// The scan's probes start at initialConcurrency in flight and adapt between
+// the minimum and maximum as probes succeed or time out.
+const (
+	initialConcurrency = 64
+	minConcurrency     = 4
+	maxConcurrency     = 1024
+)
+
+// maxBackoffShift stops retry back-off doubling after 1024 times the timeout.
+const maxBackoffShift = 10
+
+// hostScan is the per-host state shared by all probes of one host.
+type hostScan struct {
+	rtt       *util.RTTEstimator
+	remaining int
+	// start and end bracket the probes this run sent to the host.
+	start, end time.Time
+	probes     int
+	answered   int
+	open       int
+	states     map[string]int
+}
+
+// PortDiscovery probes every port on every target and returns the open ports
+// by target address. Probes are numbered host-major across the host×port space; with
+// opts.Randomize they are sent in a seeded pseudo-random order instead, which
+// interleaves hosts. Ports that time out are probed again in up to
+// opts.Retries further passes once the previous pass is over, each pass
+// waiting and allowing twice as long as the one before. Refused ports are
+// final on the first attempt.
+func PortDiscovery(ctx context.Context, hosts []Target, portsToScan []util.Port, opts PortOptions, handle Handler) (results map[string][]PortResult, err error) {
+	logger := util.NewVerboseLogger(ctx)
+
+	// Deferred first so the summary follows every in-flight probe's event.
+	stream := newStream(handle, len(hosts))
+	defer func() { stream.close(err) }()
+
+	if err := opts.Source.Validate(); err != nil {
+		return nil, err
+	}
+	via, err := newTransport(ctx, opts, portsToScan)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	if cp := opts.Checkpoint; cp != nil && (len(cp.Targets) != len(hosts) || len(cp.Ports) != len(portsToScan)) {
+		return nil, fmt.Errorf("checkpoint covers %d hosts and %d ports, not %d and %d",
+			len(cp.Targets), len(cp.Ports), len(hosts), len(portsToScan))
+	}
+
+	results = make(map[string][]PortResult)
+	scans := make([]*hostScan, len(hosts))
+	for i, host := range hosts {
+		results[host.Address] = []PortResult{}
+		// Answered probes, open or refused, tune the timeout of the host's
+		// later probes.
+		scans[i] = &hostScan{
+			rtt:       util.NewRTTEstimator(opts.Timeout, opts.MinTimeout, opts.MaxTimeout),
+			remaining: len(portsToScan),
+		}
+	}
+	var mu sync.Mutex
+	// Runs before the summary, once no probe is in flight any more.
+	defer func() {
+		var stats []HostStats
+		for i, host := range hosts {
+			if state := scans[i]; state.probes > 0 {
+				stats = append(stats, HostStats{
+					Address:  host.Address,
+					Hostname: host.Hostname,
+					Probes:   state.probes,
+					Answered: state.answered,
+					States:   state.states,
+					Open:     state.open,
+					Duration: state.end.Sub(state.start),
+					SRTT:     state.rtt.SRTT(),
+				})
+			}
+		}
+		stream.hosts(stats)
+	}()
+
+	if cp := opts.Checkpoint; cp != nil {
+		for _, result := range cp.Results {
+			results[result.Host] = append(results[result.Host], result)
+		}
+		logger.Print("Checkpoint %s: %d of %d probes already done\n", cp.Path(), cp.Completed(), len(hosts)*len(portsToScan))
+
+		ticker := time.NewTicker(checkpointInterval)
+		saved := make(chan struct{})
+		go func() {
+			defer close(saved)
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case <-ticker.C:
+					if err := cp.Save(); err != nil {
+						logger.Print("%v\n", err)
+					}
+				}
+			}
+		}()
+		defer func() {
+			ticker.Stop()
+			cancel()
+			<-saved
+			if saveErr := cp.Save(); saveErr != nil && err == nil {
+				err = saveErr
+			}
+		}()
+	}
+
+	total := uint64(len(hosts)) * uint64(len(portsToScan))
+	logger.Print("Starting port discovery with %d hosts and %d ports\n", len(hosts), len(portsToScan))
+	maximum := maxConcurrency
+	if opts.MaxConcurrency > 0 {
+		maximum = opts.MaxConcurrency
+	}
+	logger.Print("Using concurrency of %d-%d probes\n", min(minConcurrency, maximum), maximum)
+	if opts.Source.IP != nil || opts.Source.Port != 0 {
+		logger.Print("Sending probes from %s\n", opts.Source.hostPort())
+	}
+	if opts.RateLimiter != nil {
+		logger.Print("Limiting probes to one every %v\n", opts.RateLimiter.Interval())
+	}
+
+	order := func(i uint64) uint64 { return i }
+	if opts.Randomize {
+		order = util.NewPermutation(total, opts.Seed).At
+	}
+
+	limiter := util.NewConcurrencyLimiter(ctx, initialConcurrency, min(minConcurrency, maximum), maximum)
+	defer limiter.Close()
+
+	scanTarget := func(index uint64, attempt int, retry *[]uint64) error {
+		hostIndex, portIndex := index/uint64(len(portsToScan)), index%uint64(len(portsToScan))
+		host, port, state := hosts[hostIndex], portsToScan[portIndex], scans[hostIndex]
+
+		if err := opts.RateLimiter.Wait(ctx); err != nil {
+			return nil
+		}
+
+		mu.Lock()
+		if state.start.IsZero() {
+			state.start = time.Now()
+		}
+		mu.Unlock()
+
+		backoff := time.Duration(1) << min(attempt-1, maxBackoffShift)
+		sent := time.Now()
+		result := scanPort(ctx, host.Address, port, capTimeout(state.rtt.Timeout()*backoff, opts.MaxTimeout), via)
+		result.Attempts = attempt
+		result.Time = sent
+		result.Hostname = host.Hostname
+		if result.RTT > 0 {
+			state.rtt.Observe(result.RTT)
+		}
+
+		if attempt <= opts.Retries && util.IsTimeout(result.Error) {
+			mu.Lock()
+			*retry = append(*retry, index)
+			mu.Unlock()
+			return result.Error
+		}
+
+		if result.Open && result.Proto == "tcp" && opts.Inspect {
+			inspectPort(ctx, &result, opts.InspectTimeout, opts.RateLimiter)
+		}
+
+		// A probe cut short by cancellation has not learned anything.
+		if ctx.Err() != nil && !result.Open {
+			return nil
+		}
+
+		mu.Lock()
+		if result.Open {
+			results[host.Address] = append(results[host.Address], result)
+			state.open++
+		}
+		state.remaining--
+		state.probes++
+		if result.State == StateOpen || result.State == StateClosed {
+			state.answered++
+		}
+		if state.states == nil {
+			state.states = make(map[string]int)
+		}
+		state.states[result.State.String()]++
+		state.end = time.Now()
+		if state.remaining == 0 {
+			logger.Print("Scan complete for host %s: found %d open ports (smoothed RTT %v)\n", host, len(results[host.Address]), state.rtt.SRTT())
+		}
+		mu.Unlock()
+
+		opts.Checkpoint.record(index, result)
+		stream.port(result)
+		return result.Error
+	}
+
+	var retry []uint64
+	for i := uint64(0); i < total; i++ {
+		index := order(i)
+		if opts.Checkpoint.done(index) {
+			mu.Lock()
+			scans[index/uint64(len(portsToScan))].remaining--
+			mu.Unlock()
+			continue
+		}
+		if err := limiter.Execute(func() error { return scanTarget(index, 1, &retry) }); err != nil {
+			limiter.Wait()
+			return results, err
+		}
+	}
+	limiter.Wait()
+
+	for attempt := 2; len(retry) > 0; attempt++ {
+		pending := retry
+		retry = nil
+
+		var delay time.Duration
+		for _, index := range pending {
+			delay = max(delay, scans[index/uint64(len(portsToScan))].rtt.Timeout())
+		}
+		delay = capTimeout(delay<<min(attempt-2, maxBackoffShift), opts.MaxTimeout)
+		logger.Print("Retrying %d timed out probes in %v (attempt %d)\n", len(pending), delay, attempt)
+
+		select {
+		case <-ctx.Done():
+			return results, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		for _, index := range pending {
+			if err := limiter.Execute(func() error { return scanTarget(index, attempt, &retry) }); err != nil {
+				limiter.Wait()
+				return results, err
+			}
+		}
+		limiter.Wait()
+	}
+
+	logger.Print("Port discovery complete.\n")
+
+	// Probes cut short by cancellation were not reported, so a scan
+	// cancelled during its last pass is still incomplete.
+	return results, ctx.Err()
+}
+
+// newTransport sets up the proxy, if there is one, and checks that it can be
+// reached before the first probe relies on it.
+func newTransport(ctx context.Context, opts PortOptions, ports []util.Port) (transport, error) {
+	via := transport{source: opts.Source}
+	if opts.Proxy == nil {
+		return via, nil
+	}
+
+	if opts.Inspect {
+		return via, fmt.Errorf("service inspection is not supported through a proxy")
+	}
+	for _, port := range ports {
+		if port.Proto != "tcp" {
+			return via, fmt.Errorf("proxies only carry TCP, but %s was requested", port)
+		}
+	}
+
+	timeout := capTimeout(max(opts.Timeout, time.Second)*5, opts.MaxTimeout)
+	forward := opts.Source.dialer("tcp", timeout)
+	dialer, err := newProxyDialer(opts.Proxy, forward)
+	if err != nil {
+		return via, err
+	}
+
+	address := opts.Proxy.Host
+	if opts.Proxy.Port() == "" {
+		address = net.JoinHostPort(opts.Proxy.Hostname(), defaultProxyPort(opts.Proxy.Scheme))
+	}
+	conn, err := forward.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return via, fmt.Errorf("proxy %s is unreachable: %w", address, err)
+	}
+	conn.Close()
+
+	via.proxy = dialer
+	return via, nil
+}
+
+func capTimeout(timeout, maximum time.Duration) time.Duration {
+	if maximum > 0 {
+		return min(timeout, maximum)
+	}
+	return timeout
+}
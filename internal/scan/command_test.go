@@ -0,0 +1,236 @@
+package scan
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+)
+
+// collect returns a Handler that appends port results to found and stores
+// the summary, failing if anything follows it.
+func collect(found *[]PortResult, summary *Summary) Handler {
+	done := false
+	return func(event Event) {
+		if done {
+			panic("event after SummaryEvent")
+		}
+		switch event := event.(type) {
+		case PortEvent:
+			*found = append(*found, event.Result)
+		case SummaryEvent:
+			done = true
+			if summary != nil {
+				*summary = event.Summary
+			}
+		}
+	}
+}
+
+// udpServer answers every datagram after the first drop datagrams.
+func udpServer(t *testing.T, drop int) int {
+	t.Helper()
+
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for received := 0; ; received++ {
+			_, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			if received >= drop {
+				conn.WriteTo([]byte("pong"), addr)
+			}
+		}
+	}()
+
+	return conn.LocalAddr().(*net.UDPAddr).Port
+}
+
+func TestPortDiscoveryRetriesTimeouts(t *testing.T) {
+	number := udpServer(t, 1)
+	ports := []util.Port{{Number: number, Proto: "udp"}}
+	opts := PortOptions{Timeout: 100 * time.Millisecond, MaxTimeout: time.Second, Retries: 2}
+
+	var found []PortResult
+	results, err := PortDiscovery(context.Background(), []Target{{Address: "127.0.0.1"}}, ports, opts, collect(&found, nil))
+	if err != nil {
+		t.Fatalf("PortDiscovery() error: %v", err)
+	}
+
+	open := results["127.0.0.1"]
+	if len(open) != 1 || open[0].Attempts != 2 || open[0].State != StateOpen {
+		t.Fatalf("PortDiscovery() = %+v, want the port open on attempt 2", open)
+	}
+	if len(found) != 1 {
+		t.Errorf("PortDiscovery() reported %d results for one port, want only the final one", len(found))
+	}
+}
+
+func TestPortDiscoveryDoesNotRetryRefused(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	number := listener.Addr().(*net.TCPAddr).Port
+	listener.Close()
+
+	ports := []util.Port{{Number: number, Proto: "tcp"}}
+	opts := PortOptions{Timeout: 100 * time.Millisecond, Retries: 3}
+
+	var found []PortResult
+	PortDiscovery(context.Background(), []Target{{Address: "127.0.0.1"}}, ports, opts, collect(&found, nil))
+
+	result := found[0]
+	if result.State != StateClosed || result.Attempts != 1 {
+		t.Errorf("refused port: state %v after %d attempts, want closed after 1", result.State, result.Attempts)
+	}
+}
+
+func TestPortDiscoveryGivesUpAfterRetries(t *testing.T) {
+	number := udpServer(t, 100)
+	ports := []util.Port{{Number: number, Proto: "udp"}}
+	opts := PortOptions{Timeout: 20 * time.Millisecond, Retries: 2}
+
+	var found []PortResult
+	PortDiscovery(context.Background(), []Target{{Address: "127.0.0.1"}}, ports, opts, collect(&found, nil))
+
+	result := found[0]
+	if result.State != StateOpenFiltered || result.Attempts != 3 {
+		t.Errorf("silent port: state %v after %d attempts, want open|filtered after 3", result.State, result.Attempts)
+	}
+}
+
+func TestPortDiscoveryRandomizedCoversEveryProbe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	openPort := listener.Addr().(*net.TCPAddr).Port
+
+	ports := []util.Port{{Number: openPort, Proto: "tcp"}}
+	for i := 1; i <= 20; i++ {
+		if i != openPort {
+			ports = append(ports, util.Port{Number: i, Proto: "tcp"})
+		}
+	}
+	hosts := []Target{{Address: "127.0.0.1"}, {Address: "127.0.0.2"}, {Address: "127.0.0.3"}}
+	opts := PortOptions{Timeout: 200 * time.Millisecond, Randomize: true, Seed: 42}
+
+	var found []PortResult
+	results, err := PortDiscovery(context.Background(), hosts, ports, opts, collect(&found, nil))
+	if err != nil {
+		t.Fatalf("PortDiscovery() error: %v", err)
+	}
+
+	seen := make(map[string]bool)
+	for _, result := range found {
+		key := result.Host + " " + util.Port{Number: result.Port, Proto: result.Proto}.String()
+		if seen[key] {
+			t.Errorf("%s probed twice", key)
+		}
+		seen[key] = true
+	}
+	if len(seen) != len(hosts)*len(ports) {
+		t.Errorf("probed %d of %d host×port pairs", len(seen), len(hosts)*len(ports))
+	}
+	if len(results["127.0.0.1"]) != 1 || results["127.0.0.1"][0].Port != openPort {
+		t.Errorf("open ports on 127.0.0.1 = %+v, want only %d", results["127.0.0.1"], openPort)
+	}
+}
+
+func TestPortDiscoveryStreamsEveryResult(t *testing.T) {
+	ports := make([]util.Port, 0, 200)
+	for i := 1; i <= 200; i++ {
+		ports = append(ports, util.Port{Number: i, Proto: "tcp"})
+	}
+
+	// A slow consumer must hold the scan back rather than lose results.
+	var found []PortResult
+	var summary Summary
+	handle := collect(&found, &summary)
+	slow := func(event Event) {
+		time.Sleep(100 * time.Microsecond)
+		handle(event)
+	}
+
+	results, err := PortDiscovery(context.Background(), []Target{{Address: "127.0.0.1"}}, ports, PortOptions{Timeout: time.Second}, slow)
+	if err != nil {
+		t.Fatalf("PortDiscovery() error: %v", err)
+	}
+	if len(found) != len(ports) || summary.Probes != len(ports) {
+		t.Errorf("streamed %d results, summary counted %d, want %d", len(found), summary.Probes, len(ports))
+	}
+	if summary.Open != len(results["127.0.0.1"]) || summary.End.Before(summary.Start) {
+		t.Errorf("summary = %+v, disagrees with %d open ports", summary, len(results["127.0.0.1"]))
+	}
+}
+
+func TestPortDiscoverySummaryAfterCancel(t *testing.T) {
+	ports := make([]util.Port, 0, 1000)
+	for i := 1; i <= 1000; i++ {
+		ports = append(ports, util.Port{Number: i, Proto: "tcp"})
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var found []PortResult
+	var summary Summary
+	handle := collect(&found, &summary)
+	PortDiscovery(ctx, []Target{{Address: "127.0.0.1"}}, ports, PortOptions{Timeout: time.Second}, func(event Event) {
+		if len(found) == 10 {
+			cancel()
+		}
+		handle(event)
+	})
+
+	if summary.Err == nil || summary.End.IsZero() {
+		t.Errorf("summary after cancel = %+v, want the cancellation recorded", summary)
+	}
+	if summary.Probes != len(found) {
+		t.Errorf("summary counted %d probes but %d were streamed", summary.Probes, len(found))
+	}
+}
+
+func TestPortDiscoveryIPv6(t *testing.T) {
+	listener, err := net.Listen("tcp", "[::1]:0")
+	if err != nil {
+		t.Skipf("IPv6 loopback unavailable: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+
+	ports := []util.Port{{Number: listener.Addr().(*net.TCPAddr).Port, Proto: "tcp"}}
+	results, err := PortDiscovery(context.Background(), []Target{{Address: "::1"}}, ports, PortOptions{Timeout: time.Second}, nil)
+	if err != nil {
+		t.Fatalf("PortDiscovery() error: %v", err)
+	}
+	if open := results["::1"]; len(open) != 1 || open[0].State != StateOpen {
+		t.Errorf("PortDiscovery() on ::1 = %+v, want the port open", open)
+	}
+}
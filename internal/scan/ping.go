@@ -0,0 +1,251 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+	"golang.org/x/net/icmp"
+	"golang.org/x/net/ipv4"
+)
+
+func ping(ctx context.Context, host string, timeout time.Duration, source net.IP) (*HostResult, error) {
+	logger := util.NewVerboseLogger(ctx)
+	logger.Print("Pinging host %s (timeout: %v)...\n", host, timeout)
+
+	ctxWithTimeout, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	ipAddr, err := net.ResolveIPAddr("ip4", host)
+	if err != nil {
+		logger.Print("Failed to resolve host %s: %v\n", host, err)
+		return nil, fmt.Errorf("failed to resolve host: %w", err)
+	}
+
+	logger.Print("Resolved %s to %s\n", host, ipAddr.String())
+
+	listenAddr := "0.0.0.0"
+	if source != nil {
+		listenAddr = source.String()
+	}
+
+	conn, err := icmp.ListenPacket("ip4:icmp", listenAddr)
+	if err != nil {
+		logger.Print("Error listening for ICMP packets: %v\n", err)
+		return nil, fmt.Errorf("error listening for ICMP packets: %w", err)
+	}
+	defer conn.Close()
+
+	id := os.Getpid() & 0xffff
+	msg := icmp.Message{
+		Type: ipv4.ICMPTypeEcho, Code: 0,
+		Body: &icmp.Echo{
+			ID:   id,
+			Seq:  1,
+			Data: []byte("PING"),
+		},
+	}
+	msgBytes, err := msg.Marshal(nil)
+	if err != nil {
+		logger.Print("Error marshalling ICMP message: %v\n", err)
+		return nil, fmt.Errorf("error marshalling ICMP message: %w", err)
+	}
+
+	start := time.Now()
+	if _, err := conn.WriteTo(msgBytes, ipAddr); err != nil {
+		logger.Print("Error sending ICMP packet to %s: %v\n", ipAddr.String(), err)
+		return nil, fmt.Errorf("error sending ICMP packet: %w", err)
+	}
+
+	logger.Print("ICMP packet sent to %s, waiting for reply...\n", ipAddr.String())
+
+	select {
+	case <-ctxWithTimeout.Done():
+		logger.Print("Timeout waiting for response from %s\n", ipAddr.String())
+		return nil, ctxWithTimeout.Err()
+	default:
+	}
+
+	conn.SetReadDeadline(time.Now().Add(timeout))
+
+	reply := make([]byte, 1500)
+	n, _, err := conn.ReadFrom(reply)
+	if err != nil {
+		logger.Print("Error reading response from %s: %v\n", ipAddr.String(), err)
+		return nil, err
+	}
+	duration := time.Since(start)
+
+	logger.Print("Received reply from %s in %v\n", ipAddr.String(), duration)
+
+	rm, err := icmp.ParseMessage(1, reply[:n])
+	if err != nil {
+		logger.Print("Error parsing ICMP message from %s: %v\n", ipAddr.String(), err)
+		return nil, fmt.Errorf("error parsing ICMP message: %w", err)
+	}
+	if rm.Type == ipv4.ICMPTypeEchoReply {
+		return &HostResult{IP: ipAddr.String(), RTT: duration}, nil
+	}
+
+	logger.Print("Unexpected ICMP message type from %s: %v\n", ipAddr.String(), rm.Type)
+	return nil, fmt.Errorf("unexpected ICMP message type: %v", rm.Type)
+}
+
+// maxPingConcurrency caps how many pings host discovery adapts up to.
+const maxPingConcurrency = 256
+
+// HostDiscovery pings the addresses of the active interface's subnet and
+// returns the hosts that answered.
+func HostDiscovery(ctx context.Context, opts HostOptions, handle Handler) (hosts []HostResult, err error) {
+	logger := util.NewVerboseLogger(ctx)
+	maxHosts := opts.MaxHosts
+
+	// Deferred first so the summary follows every in-flight ping's event.
+	stream := newStream(handle, 0)
+	defer func() { stream.close(err) }()
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// With a source address, the subnet to sweep is that address's.
+	var ipNet *net.IPNet
+	if opts.Source.IP != nil {
+		// The sweep works on 32-bit addresses; ICMPv6 discovery is not
+		// supported.
+		if opts.Source.IP.To4() == nil {
+			return nil, fmt.Errorf("host discovery needs an IPv4 source address, not %s", opts.Source.IP)
+		}
+		if err := opts.Source.Validate(); err != nil {
+			return nil, err
+		}
+		ipNet, err = util.LocalIPNet(opts.Source.IP)
+		logger.Print("Sending pings from %s\n", opts.Source.IP)
+	} else {
+		_, ipNet, err = util.GetIPNetForActiveInterface(logger)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if ipNet.IP.To4() == nil {
+		return nil, fmt.Errorf("host discovery needs an IPv4 network, not %s", ipNet)
+	}
+
+	localIP := ipNet.IP.Mask(ipNet.Mask)
+	mask := net.IP(ipNet.Mask).To4()
+	ipUint := util.IPToUint32(localIP)
+	maskUint := util.IPToUint32(mask)
+	broadcastUint := ipUint | ^maskUint
+
+	hostCount := int(broadcastUint - ipUint - 1)
+	if maxHosts > 0 && maxHosts < hostCount {
+		hostCount = maxHosts
+	}
+
+	logger.Print("Network information:\n")
+	logger.Print("  Local IP: %s\n", localIP)
+	logger.Print("  Netmask: %s\n", mask)
+	logger.Print("  Broadcast: %s\n", util.Uint32ToIP(broadcastUint))
+	logger.Print("  Host count: %d (limited to %d)\n", broadcastUint-ipUint-1, hostCount)
+	stream.summary.Hosts = hostCount
+
+	maximum := maxPingConcurrency
+	if opts.MaxConcurrency > 0 {
+		maximum = opts.MaxConcurrency
+	}
+	concurrency := min(50, hostCount, maximum)
+	logger.Print("Using initial concurrency of %d\n", concurrency)
+	if opts.RateLimiter != nil {
+		logger.Print("Limiting pings to one every %v\n", opts.RateLimiter.Interval())
+	}
+
+	limiter := util.NewConcurrencyLimiter(ctx, concurrency, 1, min(hostCount, maximum))
+	defer limiter.Close()
+
+	logger.Print("Starting host scan from %s to %s\n", util.Uint32ToIP(ipUint+1), util.Uint32ToIP(broadcastUint-1))
+
+	var pending []string
+	for candidate := ipUint + 1; candidate < broadcastUint && len(pending) < hostCount; candidate++ {
+		pending = append(pending, util.Uint32ToIP(candidate).String())
+	}
+
+	var resultsMutex sync.Mutex
+	hosts = make([]HostResult, 0, hostCount)
+
+	// Hosts whose echo went unanswered are pinged again in up to
+	// opts.Retries further passes, each waiting and allowing twice as long
+	// as the one before.
+passes:
+	for attempt := 1; len(pending) > 0; attempt++ {
+		backoff := time.Duration(1) << min(attempt-1, maxBackoffShift)
+		if attempt > 1 {
+			logger.Print("Retrying %d silent hosts in %v (attempt %d)\n", len(pending), opts.Timeout*(backoff/2), attempt)
+			select {
+			case <-ctx.Done():
+				err = ctx.Err()
+				break passes
+			case <-time.After(opts.Timeout * (backoff / 2)):
+			}
+		}
+
+		var retry []string
+		for _, candidateIP := range pending {
+			if err = limiter.Execute(func() error {
+				if err := opts.RateLimiter.Wait(ctx); err != nil {
+					return nil
+				}
+
+				res, err := ping(ctx, candidateIP, opts.Timeout*backoff, opts.Source.IP)
+				if err == nil && res != nil {
+					res.Attempts = attempt
+
+					resultsMutex.Lock()
+					hosts = append(hosts, *res)
+					resultsMutex.Unlock()
+
+					stream.host(*res)
+					return nil
+				}
+
+				logger.Print("Host %s is not reachable: %v\n", candidateIP, err)
+				if ctx.Err() != nil {
+					return nil
+				}
+
+				// An unanswered echo usually means there is no host at that
+				// address, so only resource errors slow host discovery down.
+				if util.IsTimeout(err) && attempt <= opts.Retries {
+					resultsMutex.Lock()
+					retry = append(retry, candidateIP)
+					resultsMutex.Unlock()
+					return nil
+				}
+
+				stream.hostDown(err)
+				if util.IsTimeout(err) {
+					return nil
+				}
+				return err
+			}); err != nil {
+				break passes
+			}
+		}
+
+		logger.Print("Waiting for all ping operations to complete...\n")
+		limiter.Wait()
+		pending = retry
+	}
+
+	limiter.Wait()
+	logger.Print("Host discovery complete, found %d active hosts\n", len(hosts))
+
+	if err == nil {
+		err = ctx.Err()
+	}
+
+	return hosts, err
+}
@@ -0,0 +1,177 @@
+package scan
+
+import (
+	"bufio"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+	"golang.org/x/net/proxy"
+)
+
+// errProxyRefused reports that the proxy reached the target host and was
+// refused, so the port is closed rather than filtered.
+var errProxyRefused = errors.New("target refused the proxied connection")
+
+// proxyUnreachableError is a failure to reach the proxy itself. It says
+// nothing about the target, even when it wraps ECONNREFUSED.
+type proxyUnreachableError struct {
+	err error
+}
+
+func (e *proxyUnreachableError) Error() string {
+	return "proxy unreachable: " + e.err.Error()
+}
+
+func (e *proxyUnreachableError) Unwrap() error {
+	return e.err
+}
+
+// ParseProxy checks a --proxy URL: socks5://, socks5h:// or http:// (CONNECT),
+// with optional user:password credentials.
+func ParseProxy(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", rawURL, err)
+	}
+	switch u.Scheme {
+	case "socks5", "socks5h", "http":
+	default:
+		return nil, fmt.Errorf("unsupported proxy scheme %q (want socks5 or http)", u.Scheme)
+	}
+	if u.Hostname() == "" {
+		return nil, fmt.Errorf("proxy URL %q has no host", rawURL)
+	}
+	return u, nil
+}
+
+func defaultProxyPort(scheme string) string {
+	if scheme == "http" {
+		return "3128"
+	}
+	return "1080"
+}
+
+// newProxyDialer returns a dialer that connects through the proxy at u,
+// reaching the proxy with forward.
+func newProxyDialer(u *url.URL, forward *net.Dialer) (proxy.ContextDialer, error) {
+	forwardDialer := unreachableDialer{forward}
+
+	if u.Scheme == "http" {
+		address := u.Host
+		if u.Port() == "" {
+			address = net.JoinHostPort(u.Hostname(), defaultProxyPort(u.Scheme))
+		}
+		dialer := &httpProxyDialer{address: address, forward: forwardDialer}
+		if u.User != nil {
+			password, _ := u.User.Password()
+			dialer.auth = "Basic " + base64.StdEncoding.EncodeToString([]byte(u.User.Username()+":"+password))
+		}
+		return dialer, nil
+	}
+
+	dialer, err := proxy.FromURL(u, forwardDialer)
+	if err != nil {
+		return nil, err
+	}
+	return socksDialer{dialer.(proxy.ContextDialer)}, nil
+}
+
+// unreachableDialer marks its errors as failures to reach the proxy.
+type unreachableDialer struct {
+	dialer *net.Dialer
+}
+
+func (d unreachableDialer) Dial(network, address string) (net.Conn, error) {
+	return d.DialContext(context.Background(), network, address)
+}
+
+func (d unreachableDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, address)
+	if err != nil && !util.IsTimeout(err) {
+		return nil, &proxyUnreachableError{err: err}
+	}
+	return conn, err
+}
+
+// socksDialer turns a SOCKS "connection refused" reply into errProxyRefused.
+// The SOCKS client reports reply codes only as error text.
+type socksDialer struct {
+	dialer proxy.ContextDialer
+}
+
+func (d socksDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.dialer.DialContext(ctx, network, address)
+	var unreachable *proxyUnreachableError
+	if err != nil && !errors.As(err, &unreachable) && strings.HasSuffix(err.Error(), "connection refused") {
+		return nil, fmt.Errorf("%w: %w", errProxyRefused, err)
+	}
+	return conn, err
+}
+
+// httpProxyDialer tunnels connections through an HTTP proxy with CONNECT.
+type httpProxyDialer struct {
+	address string
+	auth    string
+	forward unreachableDialer
+}
+
+func (d *httpProxyDialer) DialContext(ctx context.Context, network, address string) (net.Conn, error) {
+	conn, err := d.forward.DialContext(ctx, "tcp", d.address)
+	if err != nil {
+		return nil, err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	stop := context.AfterFunc(ctx, func() { conn.SetDeadline(time.Unix(1, 0)) })
+	defer stop()
+
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Opaque: address},
+		Host:   address,
+		Header: make(http.Header),
+	}
+	if d.auth != "" {
+		req.Header.Set("Proxy-Authorization", d.auth)
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, &proxyUnreachableError{err: err}
+	}
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), req)
+	if err != nil {
+		conn.Close()
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, err
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		conn.SetDeadline(time.Time{})
+		return conn, nil
+	// Proxies answer 502 or 503 when their own connection attempt fails;
+	// a 504 timeout or a policy denial leaves the port filtered.
+	case resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable:
+		conn.Close()
+		return nil, fmt.Errorf("%w: CONNECT %s: %s", errProxyRefused, address, resp.Status)
+	case resp.StatusCode == http.StatusProxyAuthRequired:
+		conn.Close()
+		return nil, &proxyUnreachableError{err: fmt.Errorf("CONNECT %s: %s", address, resp.Status)}
+	default:
+		conn.Close()
+		return nil, fmt.Errorf("CONNECT %s: %s", address, resp.Status)
+	}
+}
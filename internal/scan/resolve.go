@@ -0,0 +1,127 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"github.com/jspback/bingus/internal/util"
+)
+
+// Target is one address to port scan, with the hostname it was resolved
+// from when it came from a name.
+type Target struct {
+	Hostname string
+	Address  string
+}
+
+func (t Target) String() string {
+	if t.Hostname == "" || t.Hostname == t.Address {
+		return t.Address
+	}
+	return fmt.Sprintf("%s (%s)", t.Hostname, t.Address)
+}
+
+// Resolve policies for hostnames with several addresses.
+const (
+	// ResolveFirst scans only the first address the resolver returns.
+	ResolveFirst = "first"
+	// ResolveAll scans every address.
+	ResolveAll = "all"
+)
+
+// ResolveTargets turns hosts, which may be IP addresses, bracketed IPv6
+// literals, IPv6 addresses with a zone such as fe80::1%eth0, or hostnames,
+// into the addresses to scan. network is "ip", "ip4" or "ip6" as for
+// net.Resolver; addresses of another family are dropped from hostnames and
+// rejected when given literally. policy applies to hostnames with several
+// A/AAAA records. An address reached through more than one entry is scanned
+// once, under the first hostname that led to it.
+func ResolveTargets(ctx context.Context, hosts []string, network, policy string) ([]Target, error) {
+	return resolveTargets(ctx, hosts, network, policy, net.DefaultResolver.LookupNetIP)
+}
+
+func resolveTargets(ctx context.Context, hosts []string, network, policy string, lookup func(context.Context, string, string) ([]netip.Addr, error)) ([]Target, error) {
+	if policy != ResolveFirst && policy != ResolveAll {
+		return nil, fmt.Errorf("unknown resolve policy %q (want %s or %s)", policy, ResolveFirst, ResolveAll)
+	}
+
+	logger := util.NewVerboseLogger(ctx)
+	seen := make(map[string]bool)
+	var targets []Target
+	add := func(target Target) {
+		if !seen[target.Address] {
+			seen[target.Address] = true
+			targets = append(targets, target)
+		}
+	}
+
+	for _, host := range hosts {
+		literal := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if addr, err := netip.ParseAddr(literal); err == nil {
+			addr = addr.Unmap()
+			if !inFamily(addr, network) {
+				return nil, fmt.Errorf("%s is not an %s address", host, familyName(network))
+			}
+			add(Target{Address: addr.String()})
+			continue
+		}
+
+		addrs, err := lookup(ctx, network, host)
+		if err != nil {
+			return nil, fmt.Errorf("cannot resolve %s: %w", host, err)
+		}
+		if len(addrs) == 0 {
+			return nil, fmt.Errorf("cannot resolve %s: no %s addresses", host, familyName(network))
+		}
+		logger.Print("Resolved %s to %d addresses\n", host, len(addrs))
+
+		if policy == ResolveFirst {
+			addrs = addrs[:1]
+		}
+		for _, addr := range addrs {
+			add(Target{Hostname: host, Address: addr.Unmap().String()})
+		}
+	}
+
+	return targets, nil
+}
+
+func inFamily(addr netip.Addr, network string) bool {
+	switch network {
+	case "ip4":
+		return addr.Is4()
+	case "ip6":
+		return addr.Is6()
+	default:
+		return true
+	}
+}
+
+func familyName(network string) string {
+	switch network {
+	case "ip4":
+		return "IPv4"
+	case "ip6":
+		return "IPv6"
+	default:
+		return "IP"
+	}
+}
+
+// UnresolvedTargets passes hosts through as they are, for proxies that
+// resolve hostnames themselves.
+func UnresolvedTargets(hosts []string) []Target {
+	targets := make([]Target, 0, len(hosts))
+	for _, host := range hosts {
+		literal := strings.TrimSuffix(strings.TrimPrefix(host, "["), "]")
+		if addr, err := netip.ParseAddr(literal); err == nil {
+			targets = append(targets, Target{Address: addr.Unmap().String()})
+		} else {
+			targets = append(targets, Target{Hostname: host, Address: host})
+		}
+	}
+	return targets
+}
@@ -0,0 +1,105 @@
+package scan
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"reflect"
+	"testing"
+)
+
+func fakeLookup(records map[string][]string) func(context.Context, string, string) ([]netip.Addr, error) {
+	return func(_ context.Context, network, host string) ([]netip.Addr, error) {
+		addresses, ok := records[host]
+		if !ok {
+			return nil, errors.New("no such host")
+		}
+		var addrs []netip.Addr
+		for _, address := range addresses {
+			if addr := netip.MustParseAddr(address); inFamily(addr, network) {
+				addrs = append(addrs, addr)
+			}
+		}
+		return addrs, nil
+	}
+}
+
+func TestResolveTargets(t *testing.T) {
+	lookup := fakeLookup(map[string][]string{
+		"db01":  {"10.0.0.5", "2001:db8::5"},
+		"alias": {"10.0.0.5"},
+	})
+	hosts := []string{"db01", "10.0.0.1", "alias", "10.0.0.1"}
+
+	tests := []struct {
+		network string
+		policy  string
+		want    []Target
+	}{
+		{"ip", ResolveAll, []Target{
+			{Hostname: "db01", Address: "10.0.0.5"},
+			{Hostname: "db01", Address: "2001:db8::5"},
+			{Address: "10.0.0.1"},
+		}},
+		{"ip", ResolveFirst, []Target{
+			{Hostname: "db01", Address: "10.0.0.5"},
+			{Address: "10.0.0.1"},
+		}},
+		{"ip4", ResolveAll, []Target{
+			{Hostname: "db01", Address: "10.0.0.5"},
+			{Address: "10.0.0.1"},
+		}},
+	}
+
+	for _, tt := range tests {
+		got, err := resolveTargets(context.Background(), hosts, tt.network, tt.policy, lookup)
+		if err != nil {
+			t.Fatalf("resolveTargets(%s) error: %v", tt.policy, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("resolveTargets(%s) = %+v, want %+v", tt.policy, got, tt.want)
+		}
+	}
+}
+
+func TestResolveTargetsErrors(t *testing.T) {
+	lookup := fakeLookup(map[string][]string{"empty": nil})
+	for _, host := range []string{"missing", "empty"} {
+		if _, err := resolveTargets(context.Background(), []string{host}, "ip", ResolveAll, lookup); err == nil {
+			t.Errorf("resolveTargets(%q) succeeded", host)
+		}
+	}
+	if _, err := resolveTargets(context.Background(), []string{"10.0.0.1"}, "ip", "some", lookup); err == nil {
+		t.Error("resolveTargets accepted an unknown policy")
+	}
+}
+
+func TestResolveTargetsIPv6Literals(t *testing.T) {
+	lookup := fakeLookup(nil)
+	hosts := []string{"[2001:db8::1]", "fe80::1%eth0", "::ffff:10.0.0.1"}
+
+	got, err := resolveTargets(context.Background(), hosts, "ip", ResolveAll, lookup)
+	if err != nil {
+		t.Fatalf("resolveTargets() error: %v", err)
+	}
+	want := []Target{{Address: "2001:db8::1"}, {Address: "fe80::1%eth0"}, {Address: "10.0.0.1"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("resolveTargets() = %+v, want %+v", got, want)
+	}
+
+	if _, err := resolveTargets(context.Background(), []string{"2001:db8::1"}, "ip4", ResolveAll, lookup); err == nil {
+		t.Error("resolveTargets accepted an IPv6 literal with ip4")
+	}
+	if _, err := resolveTargets(context.Background(), []string{"10.0.0.1"}, "ip6", ResolveAll, lookup); err == nil {
+		t.Error("resolveTargets accepted an IPv4 literal with ip6")
+	}
+}
+
+func TestTargetString(t *testing.T) {
+	if got := (Target{Hostname: "db01", Address: "10.0.0.5"}).String(); got != "db01 (10.0.0.5)" {
+		t.Errorf("String() = %q", got)
+	}
+	if got := (Target{Address: "10.0.0.5"}).String(); got != "10.0.0.5" {
+		t.Errorf("String() = %q", got)
+	}
+}
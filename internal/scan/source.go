@@ -0,0 +1,80 @@
+package scan
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+)
+
+// Source is the local address probes are sent from. A nil IP or zero Port
+// leaves that part of the choice to the operating system.
+type Source struct {
+	IP   net.IP
+	Port int
+}
+
+func (s Source) isZero() bool {
+	return s.IP == nil && s.Port == 0
+}
+
+// Validate checks that the address belongs to a local interface and that a
+// socket can be bound to it, so a bad source fails the scan before the first
+// probe instead of failing every probe.
+func (s Source) Validate() error {
+	if s.isZero() {
+		return nil
+	}
+	if s.Port < 0 || s.Port > 65535 {
+		return fmt.Errorf("source port %d is out of range", s.Port)
+	}
+
+	if s.IP != nil {
+		if _, err := util.LocalIPNet(s.IP); err != nil {
+			return fmt.Errorf("invalid source address: %w", err)
+		}
+	}
+
+	conn, err := net.ListenPacket("udp", s.hostPort())
+	if err != nil {
+		return fmt.Errorf("cannot bind source address %s: %w", s.hostPort(), err)
+	}
+	return conn.Close()
+}
+
+func (s Source) hostPort() string {
+	host := ""
+	if s.IP != nil {
+		host = s.IP.String()
+	}
+	return net.JoinHostPort(host, strconv.Itoa(s.Port))
+}
+
+// dialer returns a net.Dialer bound to the source. With a fixed source port,
+// concurrent probes share that port, which needs SO_REUSEADDR.
+func (s Source) dialer(proto string, timeout time.Duration) *net.Dialer {
+	dialer := &net.Dialer{Timeout: timeout}
+	if s.isZero() {
+		return dialer
+	}
+
+	switch proto {
+	case "udp":
+		dialer.LocalAddr = &net.UDPAddr{IP: s.IP, Port: s.Port}
+	default:
+		dialer.LocalAddr = &net.TCPAddr{IP: s.IP, Port: s.Port}
+	}
+	if s.Port != 0 {
+		dialer.Control = func(network, address string, c syscall.RawConn) error {
+			var err error
+			if controlErr := c.Control(func(fd uintptr) { err = setReuseAddr(fd) }); controlErr != nil {
+				return controlErr
+			}
+			return err
+		}
+	}
+	return dialer
+}
@@ -0,0 +1,94 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+)
+
+func TestSourceValidate(t *testing.T) {
+	if err := (Source{}).Validate(); err != nil {
+		t.Errorf("zero Source: %v", err)
+	}
+	if err := (Source{IP: net.ParseIP("127.0.0.1")}).Validate(); err != nil {
+		t.Errorf("loopback Source: %v", err)
+	}
+	// 192.0.2.0/24 is reserved for documentation and never assigned.
+	if err := (Source{IP: net.ParseIP("192.0.2.1")}).Validate(); err == nil {
+		t.Error("Validate accepted an address of no local interface")
+	}
+	if err := (Source{Port: 70000}).Validate(); err == nil {
+		t.Error("Validate accepted an out-of-range port")
+	}
+}
+
+func TestPortDiscoveryBindsSource(t *testing.T) {
+	var numbers []int
+	remotes := make(chan string, 2)
+	for range 2 {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("listen: %v", err)
+		}
+		t.Cleanup(func() { listener.Close() })
+		go func() {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			remotes <- conn.RemoteAddr().String()
+			conn.Close()
+		}()
+		numbers = append(numbers, listener.Addr().(*net.TCPAddr).Port)
+	}
+
+	// Borrow a free port number to send from.
+	free, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	sourcePort := free.Addr().(*net.TCPAddr).Port
+	free.Close()
+
+	ports := []util.Port{{Number: numbers[0], Proto: "tcp"}, {Number: numbers[1], Proto: "tcp"}}
+	opts := PortOptions{
+		Timeout:    time.Second,
+		MaxTimeout: time.Second,
+		Source:     Source{IP: net.ParseIP("127.0.0.1"), Port: sourcePort},
+	}
+
+	results, err := PortDiscovery(context.Background(), []Target{{Address: "127.0.0.1"}}, ports, opts, nil)
+	if err != nil {
+		t.Fatalf("PortDiscovery() error: %v", err)
+	}
+	if open := results["127.0.0.1"]; len(open) != 2 {
+		t.Fatalf("PortDiscovery() = %+v, want both ports open", open)
+	}
+
+	want := net.JoinHostPort("127.0.0.1", fmt.Sprint(sourcePort))
+	for range 2 {
+		if remote := <-remotes; remote != want {
+			t.Errorf("probe came from %s, want %s", remote, want)
+		}
+	}
+}
+
+func TestHostDiscoveryRejectsIPv6Source(t *testing.T) {
+	var summary Summary
+	_, err := HostDiscovery(context.Background(), HostOptions{Timeout: 100 * time.Millisecond, Source: Source{IP: net.ParseIP("::1")}},
+		func(event Event) {
+			if event, ok := event.(SummaryEvent); ok {
+				summary = event.Summary
+			}
+		})
+	if err == nil {
+		t.Fatal("HostDiscovery accepted an IPv6 source")
+	}
+	if summary.Err == nil {
+		t.Error("the summary does not carry the error")
+	}
+}
@@ -0,0 +1,139 @@
+package scan
+
+import (
+	"errors"
+	"slices"
+	"syscall"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+)
+
+// Stats breaks a scan's probes down, so an empty result can be told apart:
+// all closed, all filtered, or all timing out because the route went away.
+type Stats struct {
+	// States counts final results by state: port states for port scans,
+	// "up" and "down" for host discovery.
+	States map[string]int
+	// Errors counts failed probes by the kind of error; see ErrorKind.
+	Errors map[string]int
+	// Hosts has one entry per port-scanned target that was probed, in
+	// target order.
+	Hosts []HostStats
+	// RTT covers the probes that got an answer.
+	RTT RTTStats
+}
+
+// HostStats is how long one target's port scan took, from its first probe
+// to its last result. Probes, Answered, Open and States count only this run's
+// results, not those restored from a checkpoint.
+type HostStats struct {
+	Address  string
+	Hostname string
+	Probes   int
+	// Answered counts probes the host replied to, open or closed.
+	Answered int
+	Open     int
+	// States counts the host's results by port state.
+	States   map[string]int
+	Duration time.Duration
+	// SRTT is the host's smoothed round-trip time.
+	SRTT time.Duration
+}
+
+// RTTStats are nearest-rank percentiles of the measured round-trip times.
+type RTTStats struct {
+	Samples int
+	Min     time.Duration
+	P50     time.Duration
+	P90     time.Duration
+	P99     time.Duration
+	Max     time.Duration
+}
+
+// Rate is the probes finished per second over the whole scan.
+func (s Summary) Rate() float64 {
+	elapsed := s.End.Sub(s.Start).Seconds()
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(s.Probes) / elapsed
+}
+
+// ErrorKind names the kind of a failed probe's error for Stats.Errors, or
+// returns "" for nil.
+func ErrorKind(err error) string {
+	var unreachable *proxyUnreachableError
+	switch {
+	case err == nil:
+		return ""
+	case errors.As(err, &unreachable):
+		return "proxy unreachable"
+	case util.IsTimeout(err):
+		return "timeout"
+	case errors.Is(err, syscall.ECONNREFUSED), errors.Is(err, errProxyRefused):
+		return "refused"
+	case errors.Is(err, syscall.ECONNRESET):
+		return "reset"
+	case errors.Is(err, syscall.EHOSTUNREACH):
+		return "host unreachable"
+	case errors.Is(err, syscall.ENETUNREACH):
+		return "network unreachable"
+	case errors.Is(err, syscall.EACCES), errors.Is(err, syscall.EPERM):
+		return "permission denied"
+	case errors.Is(err, syscall.EMFILE), errors.Is(err, syscall.ENFILE),
+		errors.Is(err, syscall.EADDRNOTAVAIL), errors.Is(err, syscall.ENOBUFS):
+		return "local resources"
+	default:
+		return "other"
+	}
+}
+
+// statsCollector accumulates a stream's Stats.
+type statsCollector struct {
+	stats Stats
+	rtts  []time.Duration
+}
+
+func (c *statsCollector) add(state string, err error, rtt time.Duration) {
+	if c.stats.States == nil {
+		c.stats.States = make(map[string]int)
+		c.stats.Errors = make(map[string]int)
+	}
+	c.stats.States[state]++
+	if kind := ErrorKind(err); kind != "" {
+		c.stats.Errors[kind]++
+	}
+	if rtt > 0 {
+		c.rtts = append(c.rtts, rtt)
+	}
+}
+
+func (c *statsCollector) result() Stats {
+	stats := c.stats
+	stats.RTT = rttStats(c.rtts)
+	return stats
+}
+
+func rttStats(rtts []time.Duration) RTTStats {
+	if len(rtts) == 0 {
+		return RTTStats{}
+	}
+	sorted := slices.Clone(rtts)
+	slices.Sort(sorted)
+	return RTTStats{
+		Samples: len(sorted),
+		Min:     sorted[0],
+		P50:     percentile(sorted, 50),
+		P90:     percentile(sorted, 90),
+		P99:     percentile(sorted, 99),
+		Max:     sorted[len(sorted)-1],
+	}
+}
+
+// percentile is the nearest-rank pth percentile of sorted, which must not be
+// empty.
+func percentile(sorted []time.Duration, p int) time.Duration {
+	rank := (p*len(sorted) + 99) / 100
+	return sorted[max(rank, 1)-1]
+}
@@ -0,0 +1,106 @@
+package scan
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/jspback/bingus/internal/util"
+)
+
+func TestPercentile(t *testing.T) {
+	sorted := make([]time.Duration, 100)
+	for i := range sorted {
+		sorted[i] = time.Duration(i+1) * time.Millisecond
+	}
+
+	tests := []struct {
+		p    int
+		want time.Duration
+	}{
+		{0, time.Millisecond},
+		{50, 50 * time.Millisecond},
+		{90, 90 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		if got := percentile(sorted, tt.p); got != tt.want {
+			t.Errorf("percentile(1..100ms, %d) = %v, want %v", tt.p, got, tt.want)
+		}
+	}
+
+	if got := percentile([]time.Duration{7}, 99); got != 7 {
+		t.Errorf("percentile of one sample = %v, want it", got)
+	}
+}
+
+func TestErrorKind(t *testing.T) {
+	refused := &net.OpError{Op: "dial", Net: "tcp", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{nil, ""},
+		{refused, "refused"},
+		{context.DeadlineExceeded, "timeout"},
+		{fmt.Errorf("%w: %w", errProxyRefused, fmt.Errorf("socks reply")), "refused"},
+		{&proxyUnreachableError{err: refused}, "proxy unreachable"},
+		{os.NewSyscallError("connect", syscall.ENETUNREACH), "network unreachable"},
+		{os.NewSyscallError("socket", syscall.EMFILE), "local resources"},
+		{fmt.Errorf("something odd"), "other"},
+	}
+	for _, tt := range tests {
+		if got := ErrorKind(tt.err); got != tt.want {
+			t.Errorf("ErrorKind(%v) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestPortDiscoveryStats(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	defer listener.Close()
+	open := listener.Addr().(*net.TCPAddr).Port
+
+	closed, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	closedPort := closed.Addr().(*net.TCPAddr).Port
+	closed.Close()
+
+	ports := []util.Port{{Number: open, Proto: "tcp"}, {Number: closedPort, Proto: "tcp"}}
+	targets := []Target{{Hostname: "localhost", Address: "127.0.0.1"}}
+
+	var summary Summary
+	if _, err := PortDiscovery(context.Background(), targets, ports, PortOptions{Timeout: time.Second}, collect(new([]PortResult), &summary)); err != nil {
+		t.Fatalf("PortDiscovery() error: %v", err)
+	}
+
+	stats := summary.Stats
+	if stats.States["open"] != 1 || stats.States["closed"] != 1 || stats.Errors["refused"] != 1 {
+		t.Errorf("states %v, errors %v, want one open and one refused closed port", stats.States, stats.Errors)
+	}
+	if stats.RTT.Samples != 2 || stats.RTT.Min > stats.RTT.P50 || stats.RTT.P50 > stats.RTT.Max {
+		t.Errorf("RTT stats = %+v, want two ordered samples", stats.RTT)
+	}
+	if len(stats.Hosts) != 1 {
+		t.Fatalf("host stats = %+v, want one host", stats.Hosts)
+	}
+	if host := stats.Hosts[0]; host.Hostname != "localhost" || host.Probes != 2 || host.Open != 1 || host.Duration <= 0 {
+		t.Errorf("host stats = %+v, want localhost with 2 probes, 1 open", host)
+	}
+	if states := stats.Hosts[0].States; states["open"] != 1 || states["closed"] != 1 {
+		t.Errorf("host states = %v, want one open and one closed", states)
+	}
+	if summary.Rate() <= 0 {
+		t.Errorf("Rate() = %v, want positive", summary.Rate())
+	}
+}
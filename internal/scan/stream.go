@@ -0,0 +1,77 @@
+package scan
+
+import (
+	"sync"
+	"time"
+)
+
+// stream serialises a scan's events into its Handler and keeps the counts
+// and statistics reported by the final SummaryEvent.
+type stream struct {
+	mu      sync.Mutex
+	handle  Handler
+	summary Summary
+	stats   statsCollector
+}
+
+func newStream(handle Handler, hosts int) *stream {
+	return &stream{handle: handle, summary: Summary{Hosts: hosts, Start: time.Now()}}
+}
+
+func (s *stream) host(result HostResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary.Probes++
+	s.summary.Up++
+	s.stats.add("up", nil, result.RTT)
+	s.emit(HostEvent{Host: result})
+}
+
+// hostDown counts a host that never answered; it produces no event. err is
+// why the last ping failed.
+func (s *stream) hostDown(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary.Probes++
+	s.stats.add("down", err, 0)
+}
+
+func (s *stream) port(result PortResult) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary.Probes++
+	if result.Open {
+		s.summary.Open++
+	}
+	s.stats.add(result.State.String(), result.Error, result.RTT)
+	s.emit(PortEvent{Result: result})
+}
+
+// close sends the SummaryEvent. err is why the scan stopped early, if it did.
+func (s *stream) close(err error) Summary {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.summary.End = time.Now()
+	s.summary.Err = err
+	s.summary.Stats = s.stats.result()
+	s.emit(SummaryEvent{Summary: s.summary})
+	return s.summary
+}
+
+// hosts records how long each target's port scan took.
+func (s *stream) hosts(hosts []HostStats) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.stats.stats.Hosts = hosts
+}
+
+func (s *stream) emit(event Event) {
+	if s.handle != nil {
+		s.handle(event)
+	}
+}
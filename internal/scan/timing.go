@@ -0,0 +1,90 @@
+package scan
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Timing bundles the knobs that together decide how hard a scan pushes the
+// network: how long probes wait, how many run at once, how fast they are sent
+// and how often silent targets are tried again.
+type Timing struct {
+	Name string
+	// Timeout is the ping timeout and the initial port probe timeout.
+	Timeout time.Duration
+	// MaxTimeout caps RTT-derived and backed-off port probe timeouts.
+	MaxTimeout time.Duration
+	// HostConcurrency caps pings in flight during host discovery.
+	HostConcurrency int
+	// PortConcurrency caps port probes in flight.
+	PortConcurrency int
+	Rate            float64
+	ScanDelay       time.Duration
+	Retries         int
+}
+
+// CustomTiming is the profile name that applies no preset, leaving every knob
+// to its own flag or field.
+const CustomTiming = "custom"
+
+// Timings are the presets, from gentlest to fastest. "normal" matches the
+// defaults of the individual flags.
+var Timings = []Timing{
+	{
+		Name:            "polite",
+		Timeout:         time.Second,
+		MaxTimeout:      10 * time.Second,
+		HostConcurrency: 8,
+		PortConcurrency: 8,
+		ScanDelay:       400 * time.Millisecond,
+		Retries:         2,
+	},
+	{
+		Name:            "normal",
+		Timeout:         500 * time.Millisecond,
+		MaxTimeout:      5 * time.Second,
+		HostConcurrency: maxPingConcurrency,
+		PortConcurrency: maxConcurrency,
+	},
+	{
+		Name:            "aggressive",
+		Timeout:         250 * time.Millisecond,
+		MaxTimeout:      1250 * time.Millisecond,
+		HostConcurrency: 512,
+		PortConcurrency: 2048,
+		Retries:         1,
+	},
+	{
+		Name:            "insane",
+		Timeout:         100 * time.Millisecond,
+		MaxTimeout:      300 * time.Millisecond,
+		HostConcurrency: 1024,
+		PortConcurrency: 4096,
+	},
+}
+
+// TimingNames lists the accepted profile names, presets first.
+func TimingNames() []string {
+	names := make([]string, 0, len(Timings)+1)
+	for _, timing := range Timings {
+		names = append(names, timing.Name)
+	}
+	return append(names, CustomTiming)
+}
+
+// LookupTiming returns the preset called name, case-insensitively. For
+// "custom" it returns a Timing with only the name set, which callers treat as
+// "use the individual settings".
+func LookupTiming(name string) (Timing, error) {
+	name = strings.ToLower(name)
+	if name == CustomTiming {
+		return Timing{Name: CustomTiming}, nil
+	}
+	for _, timing := range Timings {
+		if timing.Name == name {
+			return timing, nil
+		}
+	}
+	return Timing{}, fmt.Errorf("unknown timing template %q (want one of %s)", name, strings.Join(TimingNames(), ", "))
+}
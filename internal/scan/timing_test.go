@@ -0,0 +1,48 @@
+package scan
+
+import "testing"
+
+func TestLookupTiming(t *testing.T) {
+	for _, name := range TimingNames() {
+		timing, err := LookupTiming(name)
+		if err != nil {
+			t.Fatalf("LookupTiming(%q) error: %v", name, err)
+		}
+		if timing.Name != name {
+			t.Errorf("LookupTiming(%q).Name = %q", name, timing.Name)
+		}
+	}
+
+	if timing, err := LookupTiming("Aggressive"); err != nil || timing.Name != "aggressive" {
+		t.Errorf("LookupTiming is case-sensitive: %+v, %v", timing, err)
+	}
+	if _, err := LookupTiming("ludicrous"); err == nil {
+		t.Error("LookupTiming accepted an unknown template")
+	}
+}
+
+func TestTimingsGetFaster(t *testing.T) {
+	for i := 1; i < len(Timings); i++ {
+		slower, faster := Timings[i-1], Timings[i]
+		if faster.Timeout > slower.Timeout || faster.MaxTimeout > slower.MaxTimeout {
+			t.Errorf("%s waits longer than %s", faster.Name, slower.Name)
+		}
+		if faster.PortConcurrency < slower.PortConcurrency || faster.HostConcurrency < slower.HostConcurrency {
+			t.Errorf("%s runs fewer probes at once than %s", faster.Name, slower.Name)
+		}
+		if faster.ScanDelay > slower.ScanDelay {
+			t.Errorf("%s delays probes longer than %s", faster.Name, slower.Name)
+		}
+	}
+}
+
+func TestNormalTimingMatchesDefaults(t *testing.T) {
+	normal, _ := LookupTiming("normal")
+	if normal.PortConcurrency != maxConcurrency || normal.HostConcurrency != maxPingConcurrency {
+		t.Errorf("normal concurrency %d/%d, want defaults %d/%d",
+			normal.PortConcurrency, normal.HostConcurrency, maxConcurrency, maxPingConcurrency)
+	}
+	if normal.Rate != 0 || normal.ScanDelay != 0 || normal.Retries != 0 {
+		t.Errorf("normal timing limits probes: %+v", normal)
+	}
+}
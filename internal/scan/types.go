@@ -0,0 +1,137 @@
+package scan
+
+import (
+	"net/url"
+	"time"
+
+	"github.com/jspback/bingus/internal/probe"
+	"github.com/jspback/bingus/internal/util"
+)
+
+type PortOptions struct {
+	Timeout        time.Duration
+	MinTimeout     time.Duration
+	MaxTimeout     time.Duration
+	Inspect        bool
+	InspectTimeout time.Duration
+	Retries        int
+	Randomize      bool
+	Seed           int64
+	RateLimiter    *util.RateLimiter
+	// MaxConcurrency caps probes in flight; 0 means the default of 1024.
+	MaxConcurrency int
+	Source         Source
+	// Proxy, if set, carries every TCP connect probe; see ParseProxy.
+	Proxy *url.URL
+	// Checkpoint, if set, skips the probes it records as done and records
+	// the ones this scan finishes, saving itself periodically.
+	Checkpoint *Checkpoint
+}
+
+type HostOptions struct {
+	Timeout     time.Duration
+	MaxHosts    int
+	Retries     int
+	RateLimiter *util.RateLimiter
+	// MaxConcurrency caps pings in flight; 0 means the default of 256.
+	MaxConcurrency int
+	// Source.IP selects the ICMP listener's address and the subnet swept;
+	// Source.Port is not used.
+	Source Source
+}
+
+// State is what a probe learned about a port.
+type State int
+
+const (
+	// StateClosed means the host answered that nothing is listening.
+	StateClosed State = iota
+	StateOpen
+	// StateFiltered means no answer arrived before the timeout.
+	StateFiltered
+	// StateOpenFiltered is a silent UDP port: open services often ignore
+	// an empty datagram, so silence does not tell open and filtered apart.
+	StateOpenFiltered
+)
+
+func (s State) String() string {
+	switch s {
+	case StateOpen:
+		return "open"
+	case StateFiltered:
+		return "filtered"
+	case StateOpenFiltered:
+		return "open|filtered"
+	default:
+		return "closed"
+	}
+}
+
+type PortResult struct {
+	// Host is the address probed; Hostname is the name it was resolved
+	// from, if any.
+	Host     string
+	Hostname string
+	Port     int
+	Proto    string
+	Service  string
+	State    State
+	Open     bool
+	RTT      time.Duration
+	Attempts int
+	// Time is when the final attempt was sent.
+	Time      time.Time
+	Error     error `json:"-"`
+	TLS       *probe.TLSInfo
+	HTTP      *probe.HTTPInfo
+	HTTPError error `json:"-"`
+	SSH       *probe.SSHInfo
+}
+
+type HostResult struct {
+	IP       string
+	RTT      time.Duration
+	Attempts int
+}
+
+// Event is one item of a scan's result stream. Every probe's final result is
+// delivered exactly once, and a SummaryEvent always comes last.
+type Event interface {
+	event()
+}
+
+// HostEvent reports a host that answered host discovery.
+type HostEvent struct {
+	Host HostResult
+}
+
+// PortEvent reports the final result of probing one port.
+type PortEvent struct {
+	Result PortResult
+}
+
+// SummaryEvent ends the stream.
+type SummaryEvent struct {
+	Summary Summary
+}
+
+func (HostEvent) event()    {}
+func (PortEvent) event()    {}
+func (SummaryEvent) event() {}
+
+type Summary struct {
+	Hosts  int
+	Probes int
+	Up     int
+	Open   int
+	Start  time.Time
+	End    time.Time
+	// Err is why the scan stopped early, if it did.
+	Err   error
+	Stats Stats
+}
+
+// Handler consumes a scan's events. It is called from one goroutine at a
+// time, and probes wait while it runs, so a slow handler slows the scan down
+// instead of losing results.
+type Handler func(Event)
@@ -0,0 +1,145 @@
+package services
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const MaxPort = 65535
+
+//go:embed services.txt
+var servicesData string
+
+//go:embed top-ports.txt
+var topPortsData string
+
+// CommonPorts is the default port set used by both front ends.
+var CommonPorts = []int{
+	20, 21, 22, 23, 25, 53, 80, 110, 139, 143,
+	443, 445, 465, 587, 631, 993, 995, 1433, 1521, 3306,
+	3389, 5432, 5900, 8080, 8443,
+}
+
+// SetNames lists the named port sets accepted by Set, in display order.
+var SetNames = []string{"common", "top100", "all"}
+
+// ErrUnknownSet is returned by Set for a name that is not a port set.
+var ErrUnknownSet = errors.New("unknown port set")
+
+type serviceKey struct {
+	port  int
+	proto string
+}
+
+var (
+	names  = make(map[serviceKey]string)
+	ports  = make(map[string]map[string]int)
+	ranked []int
+)
+
+func init() {
+	scanner := bufio.NewScanner(strings.NewReader(servicesData))
+	for scanner.Scan() {
+		line, _, _ := strings.Cut(scanner.Text(), "#")
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+
+		portStr, proto, ok := strings.Cut(fields[1], "/")
+		port, err := strconv.Atoi(portStr)
+		if !ok || err != nil {
+			continue
+		}
+
+		key := serviceKey{port: port, proto: proto}
+		if _, exists := names[key]; !exists {
+			names[key] = fields[0]
+		}
+
+		for _, name := range append([]string{fields[0]}, fields[2:]...) {
+			name = strings.ToLower(name)
+			if ports[name] == nil {
+				ports[name] = make(map[string]int)
+			}
+			if _, exists := ports[name][proto]; !exists {
+				ports[name][proto] = port
+			}
+		}
+	}
+
+	for _, line := range strings.Split(topPortsData, "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		for _, field := range strings.Fields(line) {
+			if port, err := strconv.Atoi(field); err == nil {
+				ranked = append(ranked, port)
+			}
+		}
+	}
+}
+
+// Name returns the registered service name for port, or "" if there is none.
+func Name(port int, proto string) string {
+	return names[serviceKey{port: port, proto: proto}]
+}
+
+// Port looks up a service name or alias, case-insensitively.
+func Port(name, proto string) (int, bool) {
+	port, ok := ports[strings.ToLower(name)][proto]
+	return port, ok
+}
+
+// TopPorts returns the n most frequently open TCP ports in ascending order.
+// Only the ports in the embedded frequency list are ranked, so n may not be
+// more than its length.
+func TopPorts(n int) ([]int, error) {
+	if n < 1 || n > len(ranked) {
+		return nil, fmt.Errorf("only %d ports are ranked by how often they are open: use top1 to top%d", len(ranked), len(ranked))
+	}
+
+	result := slices.Clone(ranked[:n])
+	slices.Sort(result)
+	return result, nil
+}
+
+// Set expands a named port set: "common", "all" or "top<N>" such as "top100".
+// Names that are not sets return ErrUnknownSet.
+func Set(name string) ([]int, error) {
+	name = strings.ToLower(name)
+
+	switch {
+	case name == "common":
+		return slices.Clone(CommonPorts), nil
+
+	case name == "all":
+		all := make([]int, MaxPort)
+		for i := range all {
+			all[i] = i + 1
+		}
+		return all, nil
+
+	case strings.HasPrefix(name, "top"):
+		n, err := strconv.Atoi(name[len("top"):])
+		if err != nil {
+			return nil, ErrUnknownSet
+		}
+		return TopPorts(n)
+	}
+
+	return nil, ErrUnknownSet
+}
+
+// Format renders a port the way results are printed, e.g. "443/tcp https".
+func Format(port int, proto string) string {
+	if name := Name(port, proto); name != "" {
+		return fmt.Sprintf("%d/%s %s", port, proto, name)
+	}
+	return fmt.Sprintf("%d/%s", port, proto)
+}
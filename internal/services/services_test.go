@@ -0,0 +1,122 @@
+package services
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestName(t *testing.T) {
+	tests := []struct {
+		port  int
+		proto string
+		want  string
+	}{
+		{22, "tcp", "ssh"},
+		{443, "tcp", "https"},
+		{5900, "tcp", "rfb"},
+		{53, "udp", "domain"},
+		{1, "udp", ""},
+		{65000, "tcp", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Name(tt.port, tt.proto); got != tt.want {
+			t.Errorf("Name(%d, %q) = %q, want %q", tt.port, tt.proto, got, tt.want)
+		}
+	}
+}
+
+func TestPort(t *testing.T) {
+	tests := []struct {
+		name string
+		want int
+		ok   bool
+	}{
+		{"ssh", 22, true},
+		{"HTTPS", 443, true},
+		{"www", 80, true},
+		{"postgresql", 5432, true},
+		{"http-alt", 8080, true},
+		{"no-such-service", 0, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := Port(tt.name, "tcp")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("Port(%q) = %d, %v; want %d, %v", tt.name, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestTopPorts(t *testing.T) {
+	if len(ranked) != 100 {
+		t.Fatalf("embedded frequency list has %d ports, want 100", len(ranked))
+	}
+
+	for _, n := range []int{1, 20, 100} {
+		ports, err := TopPorts(n)
+		if err != nil || len(ports) != n {
+			t.Errorf("TopPorts(%d) returned %d ports, %v", n, len(ports), err)
+		}
+		if !slices.IsSorted(ports) || len(slices.Compact(slices.Clone(ports))) != len(ports) {
+			t.Errorf("TopPorts(%d) is not sorted and unique", n)
+		}
+	}
+
+	if got, _ := TopPorts(1); !slices.Equal(got, []int{80}) {
+		t.Errorf("TopPorts(1) = %v, want [80]", got)
+	}
+	top100, _ := TopPorts(100)
+	for _, port := range []int{21, 22, 23, 25, 53, 80, 443, 445, 3306, 3389, 8080} {
+		if !slices.Contains(top100, port) {
+			t.Errorf("TopPorts(100) is missing port %d", port)
+		}
+	}
+	// Only ranked ports, not ones filled in to reach n.
+	for _, port := range top100 {
+		if !slices.Contains(ranked, port) {
+			t.Errorf("TopPorts(100) has unranked port %d", port)
+		}
+	}
+
+	for _, n := range []int{0, 101, 1000, MaxPort} {
+		if ports, err := TopPorts(n); err == nil {
+			t.Errorf("TopPorts(%d) = %d ports, want an error", n, len(ports))
+		}
+	}
+}
+
+func TestSet(t *testing.T) {
+	for _, name := range SetNames {
+		if ports, err := Set(name); err != nil || len(ports) == 0 {
+			t.Errorf("Set(%q) = %d ports, %v", name, len(ports), err)
+		}
+	}
+
+	if all, _ := Set("all"); len(all) != MaxPort || all[0] != 1 || all[len(all)-1] != MaxPort {
+		t.Errorf("Set(\"all\") does not cover 1-%d", MaxPort)
+	}
+	if top, err := Set("TOP20"); err != nil || len(top) != 20 {
+		t.Errorf("Set(\"TOP20\") = %d ports, %v", len(top), err)
+	}
+	for _, name := range []string{"top0", "top1000", "top70000"} {
+		if _, err := Set(name); err == nil || errors.Is(err, ErrUnknownSet) {
+			t.Errorf("Set(%q) error = %v, want one about the ranked ports", name, err)
+		}
+	}
+	for _, name := range []string{"topx", "ssh", ""} {
+		if _, err := Set(name); !errors.Is(err, ErrUnknownSet) {
+			t.Errorf("Set(%q) error = %v, want ErrUnknownSet", name, err)
+		}
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format(443, "tcp"); got != "443/tcp https" {
+		t.Errorf("Format(443) = %q", got)
+	}
+	if got := Format(65000, "tcp"); got != "65000/tcp" {
+		t.Errorf("Format(65000) = %q", got)
+	}
+}
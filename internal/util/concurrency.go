@@ -0,0 +1,171 @@
+package util
+
+import (
+	"context"
+	"errors"
+	"net"
+	"os"
+	"sync"
+	"syscall"
+)
+
+// ConcurrencyLimiter runs functions in parallel with an adaptive limit. The
+// limit grows while work succeeds, doubling per window below the slow-start
+// threshold and by one per window above it, and is halved when work fails
+// with a congestion error. Failures from work started before the last
+// back-off are ignored, so a burst of timeouts from one window halves the
+// limit once, as in TCP congestion control.
+//
+// Running out of local resources always backs off. A timeout only does when
+// answers have come back since the last back-off and timeouts are rising
+// above their usual share, so a filtered target, which never answers, does
+// not drag the limit down to the minimum.
+type ConcurrencyLimiter struct {
+	mu       sync.Mutex
+	limit    float64
+	minimum  int
+	maximum  int
+	ssthresh float64
+	inFlight int
+	epoch    int
+	changed  chan struct{}
+
+	// answered counts work that got an answer since the last back-off.
+	answered int
+	// recentLoss and baseLoss are the share of work timing out, averaged
+	// over the last few results and over many.
+	recentLoss float64
+	baseLoss   float64
+
+	wg     sync.WaitGroup
+	ctx    context.Context
+	cancel context.CancelFunc
+	logger *VerboseLogger
+}
+
+// Timeouts are averaged over about the last 8 results and the last 128; a
+// timeout backs off once the recent share is lossMargin above the long one.
+const (
+	recentLossWeight = 1.0 / 8
+	baseLossWeight   = 1.0 / 128
+	lossMargin       = 0.25
+)
+
+func NewConcurrencyLimiter(ctx context.Context, initial, minimum, maximum int) *ConcurrencyLimiter {
+	ctx, cancel := context.WithCancel(ctx)
+	minimum = max(minimum, 1)
+	maximum = max(maximum, minimum)
+	return &ConcurrencyLimiter{
+		limit:    float64(min(max(initial, minimum), maximum)),
+		minimum:  minimum,
+		maximum:  maximum,
+		ssthresh: float64(maximum),
+		changed:  make(chan struct{}),
+		ctx:      ctx,
+		cancel:   cancel,
+		logger:   NewVerboseLogger(ctx),
+	}
+}
+
+// Execute waits for a free slot and runs fn in a new goroutine. The error fn
+// returns is only used to adapt the limit.
+func (c *ConcurrencyLimiter) Execute(fn func() error) error {
+	for {
+		c.mu.Lock()
+		if c.inFlight < int(c.limit) {
+			c.inFlight++
+			epoch := c.epoch
+			c.mu.Unlock()
+
+			c.wg.Add(1)
+			go func() {
+				defer c.wg.Done()
+				c.release(epoch, fn())
+			}()
+			return nil
+		}
+		changed := c.changed
+		c.mu.Unlock()
+
+		select {
+		case <-c.ctx.Done():
+			return c.ctx.Err()
+		case <-changed:
+		}
+	}
+}
+
+func (c *ConcurrencyLimiter) release(epoch int, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.inFlight--
+
+	timeout := IsTimeout(err)
+	switch {
+	case timeout:
+		c.recentLoss += (1 - c.recentLoss) * recentLossWeight
+		c.baseLoss += (1 - c.baseLoss) * baseLossWeight
+	case !IsCongestion(err):
+		c.answered++
+		c.recentLoss -= c.recentLoss * recentLossWeight
+		c.baseLoss -= c.baseLoss * baseLossWeight
+	}
+
+	switch {
+	case timeout && (c.answered == 0 || c.recentLoss <= c.baseLoss+lossMargin):
+		// Unanswered, but nothing suggests the scan is losing probes
+		// it would otherwise get answers to: keep the limit.
+	case IsCongestion(err):
+		if epoch == c.epoch {
+			c.epoch++
+			c.answered = 0
+			c.ssthresh = max(c.limit/2, float64(c.minimum))
+			c.limit = c.ssthresh
+			c.logger.Print("Backing off to %d concurrent probes: %v\n", int(c.limit), err)
+		}
+	case c.limit < c.ssthresh:
+		c.limit = min(c.limit+1, float64(c.maximum))
+	default:
+		c.limit = min(c.limit+1/c.limit, float64(c.maximum))
+	}
+
+	close(c.changed)
+	c.changed = make(chan struct{})
+}
+
+// Limit reports the current number of functions allowed to run at once.
+func (c *ConcurrencyLimiter) Limit() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return int(c.limit)
+}
+
+func (c *ConcurrencyLimiter) Wait() {
+	c.wg.Wait()
+}
+
+func (c *ConcurrencyLimiter) Cancel() {
+	c.cancel()
+}
+
+func (c *ConcurrencyLimiter) Close() {
+	c.Wait()
+	c.cancel()
+}
+
+// IsTimeout reports whether err is a probe that went unanswered.
+func IsTimeout(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, context.DeadlineExceeded) || errors.Is(err, os.ErrDeadlineExceeded) ||
+		(errors.As(err, &netErr) && netErr.Timeout())
+}
+
+// IsCongestion reports whether err suggests the scan is going too fast for
+// the network or the local machine: timeouts, and running out of file
+// descriptors, ephemeral ports or socket buffers.
+func IsCongestion(err error) bool {
+	return IsTimeout(err) ||
+		errors.Is(err, syscall.EMFILE) || errors.Is(err, syscall.ENFILE) ||
+		errors.Is(err, syscall.EADDRNOTAVAIL) || errors.Is(err, syscall.ENOBUFS)
+}
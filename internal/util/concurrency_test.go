@@ -0,0 +1,136 @@
+package util
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestConcurrencyLimiterGrows(t *testing.T) {
+	limiter := NewConcurrencyLimiter(context.Background(), 2, 1, 16)
+	defer limiter.Close()
+
+	for range 64 {
+		limiter.Execute(func() error { return nil })
+	}
+	limiter.Wait()
+
+	if got := limiter.Limit(); got != 16 {
+		t.Errorf("Limit() = %d after 64 successes, want the maximum of 16", got)
+	}
+}
+
+func TestConcurrencyLimiterBacksOffOncePerWindow(t *testing.T) {
+	limiter := NewConcurrencyLimiter(context.Background(), 16, 2, 16)
+	defer limiter.Close()
+
+	// The target answers at first...
+	for range 32 {
+		limiter.Execute(func() error { return nil })
+	}
+	limiter.Wait()
+
+	// ...then sixteen probes in flight together all time out: one back-off.
+	var started sync.WaitGroup
+	started.Add(16)
+	release := make(chan struct{})
+	for range 16 {
+		limiter.Execute(func() error {
+			started.Done()
+			<-release
+			return os.ErrDeadlineExceeded
+		})
+	}
+	started.Wait()
+	close(release)
+	limiter.Wait()
+
+	if got := limiter.Limit(); got != 8 {
+		t.Errorf("Limit() = %d after one window of timeouts, want 8", got)
+	}
+
+	// Later windows keep halving down to the minimum.
+	for range 4 {
+		limiter.Execute(func() error { return fmt.Errorf("dial: %w", syscall.EMFILE) })
+		limiter.Wait()
+	}
+	if got := limiter.Limit(); got != 2 {
+		t.Errorf("Limit() = %d, want the minimum of 2", got)
+	}
+}
+
+func TestConcurrencyLimiterKeepsLimitWhenNothingAnswers(t *testing.T) {
+	limiter := NewConcurrencyLimiter(context.Background(), 64, 4, 1024)
+	defer limiter.Close()
+
+	// A filtered target: every probe times out.
+	for range 2000 {
+		limiter.Execute(func() error { return os.ErrDeadlineExceeded })
+	}
+	limiter.Wait()
+
+	if got := limiter.Limit(); got != 64 {
+		t.Errorf("Limit() = %d after an all-filtered target, want it kept at 64", got)
+	}
+}
+
+func TestConcurrencyLimiterIgnoresSteadyTimeouts(t *testing.T) {
+	limiter := NewConcurrencyLimiter(context.Background(), 64, 4, 64)
+	defer limiter.Close()
+
+	// A host with a few filtered ports among answering ones: timeouts are
+	// part of its usual results, not a sign of loss.
+	for i := range 2000 {
+		limiter.Execute(func() error {
+			if i%10 == 0 {
+				return os.ErrDeadlineExceeded
+			}
+			return nil
+		})
+	}
+	limiter.Wait()
+
+	if got := limiter.Limit(); got != 64 {
+		t.Errorf("Limit() = %d with one probe in ten timing out, want 64", got)
+	}
+}
+
+func TestConcurrencyLimiterBound(t *testing.T) {
+	limiter := NewConcurrencyLimiter(context.Background(), 4, 4, 4)
+	defer limiter.Close()
+
+	var running, peak atomic.Int32
+	for range 32 {
+		limiter.Execute(func() error {
+			n := running.Add(1)
+			for {
+				old := peak.Load()
+				if n <= old || peak.CompareAndSwap(old, n) {
+					break
+				}
+			}
+			time.Sleep(time.Millisecond)
+			running.Add(-1)
+			return nil
+		})
+	}
+	limiter.Wait()
+
+	if peak.Load() > 4 {
+		t.Errorf("%d functions ran at once, limit was 4", peak.Load())
+	}
+}
+
+func TestIsCongestion(t *testing.T) {
+	if !IsCongestion(os.ErrDeadlineExceeded) || !IsCongestion(fmt.Errorf("x: %w", syscall.EADDRNOTAVAIL)) {
+		t.Error("IsCongestion() missed a timeout or EADDRNOTAVAIL")
+	}
+	if IsCongestion(nil) || IsCongestion(syscall.ECONNREFUSED) {
+		t.Error("IsCongestion() flagged a refused connection")
+	}
+}
@@ -52,6 +52,11 @@ func GetIPNetForActiveInterface(logger *VerboseLogger) (net.Interface, *net.IPNe
 
 const MaxIPsFromCIDR = 1000
 
+// ParseCIDR expands an IPv4 or IPv6 CIDR range into its host addresses. For
+// IPv4 the network and broadcast addresses are left out; IPv6 has no
+// broadcast address, so every address is kept. Ranges larger than
+// MaxIPsFromCIDR are rejected before anything is expanded, which matters
+// for IPv6 prefixes whose size does not fit in an int.
 func ParseCIDR(cidr string, logger *VerboseLogger) ([]string, error) {
 	ip, ipNet, err := net.ParseCIDR(cidr)
 	if err != nil {
@@ -59,11 +64,13 @@ func ParseCIDR(cidr string, logger *VerboseLogger) ([]string, error) {
 	}
 
 	ones, bits := ipNet.Mask.Size()
-	totalIPs := 1 << (bits - ones)
-
-	if totalIPs > MaxIPsFromCIDR {
-		return nil, fmt.Errorf("CIDR range %s contains %d addresses, which exceeds the maximum of %d. Please use a smaller range",
-			cidr, totalIPs, MaxIPsFromCIDR)
+	if hostBits := bits - ones; hostBits >= 32 || 1<<hostBits > MaxIPsFromCIDR {
+		total := fmt.Sprintf("2^%d", hostBits)
+		if hostBits < 32 {
+			total = fmt.Sprint(1 << hostBits)
+		}
+		return nil, fmt.Errorf("CIDR range %s contains %s addresses, which exceeds the maximum of %d. Please use a smaller range",
+			cidr, total, MaxIPsFromCIDR)
 	}
 
 	var ips []string
@@ -71,7 +78,7 @@ func ParseCIDR(cidr string, logger *VerboseLogger) ([]string, error) {
 		ips = append(ips, ip.String())
 	}
 
-	if len(ips) > 2 {
+	if ip.To4() != nil && len(ips) > 2 {
 		ips = ips[1 : len(ips)-1]
 	}
 
@@ -87,3 +94,20 @@ func incrementIP(ip net.IP) {
 		}
 	}
 }
+
+// LocalIPNet returns the network of the local interface address ip, or an
+// error if no interface has that address.
+func LocalIPNet(ip net.IP) (*net.IPNet, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving interface addresses: %w", err)
+	}
+
+	for _, addr := range addrs {
+		if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(ip) {
+			return ipnet, nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s is not an address of any local interface", ip)
+}
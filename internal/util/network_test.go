@@ -0,0 +1,41 @@
+package util
+
+import (
+	"context"
+	"testing"
+)
+
+func TestParseCIDR(t *testing.T) {
+	logger := NewVerboseLogger(context.Background())
+
+	tests := []struct {
+		cidr  string
+		count int
+		first string
+		last  string
+	}{
+		{"192.168.1.0/30", 2, "192.168.1.1", "192.168.1.2"},
+		{"10.0.0.7/32", 1, "10.0.0.7", "10.0.0.7"},
+		{"2001:db8::/126", 4, "2001:db8::", "2001:db8::3"},
+		{"2001:db8::ff/128", 1, "2001:db8::ff", "2001:db8::ff"},
+	}
+
+	for _, tt := range tests {
+		ips, err := ParseCIDR(tt.cidr, logger)
+		if err != nil {
+			t.Fatalf("ParseCIDR(%q) error: %v", tt.cidr, err)
+		}
+		if len(ips) != tt.count || ips[0] != tt.first || ips[len(ips)-1] != tt.last {
+			t.Errorf("ParseCIDR(%q) = %v, want %d addresses %s..%s", tt.cidr, ips, tt.count, tt.first, tt.last)
+		}
+	}
+}
+
+func TestParseCIDRTooLarge(t *testing.T) {
+	logger := NewVerboseLogger(context.Background())
+	for _, cidr := range []string{"10.0.0.0/16", "0.0.0.0/0", "2001:db8::/64", "::/0"} {
+		if _, err := ParseCIDR(cidr, logger); err == nil {
+			t.Errorf("ParseCIDR(%q) expanded a range above the maximum", cidr)
+		}
+	}
+}
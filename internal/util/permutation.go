@@ -0,0 +1,57 @@
+package util
+
+import "math/bits"
+
+// Permutation is a seeded, pseudo-random bijection on [0, n). It is a small
+// Feistel network over the smallest even number of bits that covers n,
+// cycle-walking outputs that fall outside the range, so At can be evaluated
+// for any index without materialising the permuted sequence.
+type Permutation struct {
+	n        uint64
+	halfBits uint
+	mask     uint64
+	keys     [feistelRounds]uint64
+}
+
+const feistelRounds = 4
+
+func NewPermutation(n uint64, seed int64) *Permutation {
+	p := &Permutation{n: n}
+
+	width := uint(bits.Len64(max(n, 2) - 1))
+	p.halfBits = (width + 1) / 2
+	p.mask = 1<<p.halfBits - 1
+
+	state := uint64(seed)
+	for i := range p.keys {
+		state = splitmix64(state)
+		p.keys[i] = state
+	}
+	return p
+}
+
+// At returns the element at position i of the permutation, for i < n.
+func (p *Permutation) At(i uint64) uint64 {
+	x := i
+	for {
+		x = p.encrypt(x)
+		if x < p.n {
+			return x
+		}
+	}
+}
+
+func (p *Permutation) encrypt(x uint64) uint64 {
+	left, right := x>>p.halfBits, x&p.mask
+	for _, key := range p.keys {
+		left, right = right, left^(splitmix64(right^key)&p.mask)
+	}
+	return left<<p.halfBits | right
+}
+
+func splitmix64(x uint64) uint64 {
+	x += 0x9e3779b97f4a7c15
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	return x ^ x>>31
+}
@@ -0,0 +1,41 @@
+package util
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestPermutationIsBijection(t *testing.T) {
+	for _, n := range []uint64{1, 2, 3, 17, 1000, 4096, 65535 * 3} {
+		p := NewPermutation(n, 7)
+		seen := make([]bool, n)
+		for i := uint64(0); i < n; i++ {
+			x := p.At(i)
+			if x >= n || seen[x] {
+				t.Fatalf("n=%d: At(%d) = %d is out of range or repeated", n, i, x)
+			}
+			seen[x] = true
+		}
+	}
+}
+
+func TestPermutationSeed(t *testing.T) {
+	sequence := func(seed int64) []uint64 {
+		p := NewPermutation(100, seed)
+		var out []uint64
+		for i := uint64(0); i < 100; i++ {
+			out = append(out, p.At(i))
+		}
+		return out
+	}
+
+	if !slices.Equal(sequence(1), sequence(1)) {
+		t.Error("the same seed gave different orders")
+	}
+	if slices.Equal(sequence(1), sequence(2)) {
+		t.Error("different seeds gave the same order")
+	}
+	if slices.IsSorted(sequence(1)) {
+		t.Error("the permutation left the order unchanged")
+	}
+}
@@ -0,0 +1,189 @@
+package util
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jspback/bingus/internal/services"
+)
+
+// Port is a port number together with the transport protocol to scan it over.
+type Port struct {
+	Number int
+	Proto  string
+}
+
+func (p Port) String() string {
+	return fmt.Sprintf("%d/%s", p.Number, p.Proto)
+}
+
+// PortSpecError reports where in a port specification parsing failed.
+type PortSpecError struct {
+	Spec   string
+	Offset int
+	Msg    string
+}
+
+func (e *PortSpecError) Error() string {
+	return fmt.Sprintf("invalid port specification at column %d: %s\n  %s\n  %s^",
+		e.Offset+1, e.Msg, e.Spec, strings.Repeat(" ", e.Offset))
+}
+
+// ParsePortSpec parses a comma-separated port specification. Entries are port
+// numbers, ranges (80-100, -1024, 60000-), service names (ssh) or named sets
+// (top100). A T: or U: prefix switches the protocol for the entry and every
+// entry after it; TCP is used until the first prefix. Entries starting with !
+// are excluded from the result wherever they appear. Entries may overlap, as
+// in 1-1000,443; each port is scanned once. The result is sorted, TCP before
+// UDP.
+func ParsePortSpec(spec string, logger *VerboseLogger) ([]Port, error) {
+	proto := "tcp"
+	included := make(map[Port]bool)
+	excluded := make(map[Port]bool)
+
+	offset := 0
+	for _, term := range strings.Split(spec, ",") {
+		pos := offset + len(term) - len(strings.TrimLeft(term, " \t"))
+		offset += len(term) + 1
+		term = strings.TrimSpace(term)
+
+		fail := func(at int, format string, args ...any) error {
+			return &PortSpecError{Spec: spec, Offset: at, Msg: fmt.Sprintf(format, args...)}
+		}
+
+		if term == "" {
+			return nil, fail(pos, "empty entry")
+		}
+
+		if len(term) >= 2 && term[1] == ':' {
+			switch strings.ToUpper(term[:1]) {
+			case "T":
+				proto = "tcp"
+			case "U":
+				proto = "udp"
+			default:
+				return nil, fail(pos, "unknown protocol prefix %q (want T: or U:)", term[:2])
+			}
+			term = term[2:]
+			pos += 2
+			if term == "" {
+				return nil, fail(pos, "missing ports after protocol prefix")
+			}
+		}
+
+		exclude := strings.HasPrefix(term, "!")
+		if exclude {
+			term = term[1:]
+			pos++
+		}
+
+		ports, err := parsePortTerm(term, proto)
+		if err != nil {
+			return nil, fail(pos+err.Offset, "%s", err.Msg)
+		}
+
+		target := included
+		if exclude {
+			target = excluded
+		}
+		for _, number := range ports {
+			target[Port{Number: number, Proto: proto}] = true
+		}
+
+		if exclude {
+			logger.Print("Excluding %s (%d %s ports)\n", term, len(ports), proto)
+		} else {
+			logger.Print("Adding %s (%d %s ports)\n", term, len(ports), proto)
+		}
+	}
+
+	var result []Port
+	for port := range included {
+		if !excluded[port] {
+			result = append(result, port)
+		}
+	}
+
+	if len(result) == 0 {
+		return nil, fmt.Errorf("port specification %q leaves no ports to scan", spec)
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Proto != result[j].Proto {
+			return result[i].Proto == "tcp"
+		}
+		return result[i].Number < result[j].Number
+	})
+
+	return result, nil
+}
+
+// parsePortTerm expands a single entry with its prefixes removed. Error
+// offsets are relative to the start of term.
+func parsePortTerm(term, proto string) (ports []int, err *PortSpecError) {
+	// Set and service names are checked first: names such as "http-alt"
+	// contain a hyphen and would otherwise be read as ranges.
+	set, setErr := services.Set(term)
+	if setErr == nil {
+		return set, nil
+	}
+	if !errors.Is(setErr, services.ErrUnknownSet) {
+		return nil, &PortSpecError{Msg: setErr.Error()}
+	}
+	if port, ok := services.Port(term, proto); ok {
+		return []int{port}, nil
+	}
+
+	if term[0] != '-' && (term[0] < '0' || term[0] > '9') {
+		return nil, &PortSpecError{Msg: fmt.Sprintf("%q is not a port number, service name or port set", term)}
+	}
+
+	low, high, isRange := strings.Cut(term, "-")
+	if low == "" && high == "" && isRange {
+		return nil, &PortSpecError{Msg: "range needs at least one bound"}
+	}
+	if !isRange {
+		port, err := parsePortNumber(term, 0)
+		if err != nil {
+			return nil, err
+		}
+		return []int{port}, nil
+	}
+
+	start, end := 1, services.MaxPort
+	if low != "" {
+		if start, err = parsePortNumber(low, 0); err != nil {
+			return nil, err
+		}
+	}
+	if high != "" {
+		if end, err = parsePortNumber(high, len(low)+1); err != nil {
+			return nil, err
+		}
+	}
+	if start > end {
+		return nil, &PortSpecError{Msg: fmt.Sprintf("range %d-%d is reversed", start, end)}
+	}
+
+	for port := start; port <= end; port++ {
+		ports = append(ports, port)
+	}
+	return ports, nil
+}
+
+func parsePortNumber(s string, offset int) (int, *PortSpecError) {
+	for i, r := range s {
+		if r < '0' || r > '9' {
+			return 0, &PortSpecError{Offset: offset + i, Msg: fmt.Sprintf("unexpected %q in port number", r)}
+		}
+	}
+
+	port, err := strconv.Atoi(s)
+	if err != nil || port < 1 || port > services.MaxPort {
+		return 0, &PortSpecError{Offset: offset, Msg: fmt.Sprintf("port %s is out of range (1-%d)", s, services.MaxPort)}
+	}
+	return port, nil
+}
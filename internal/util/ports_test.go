@@ -0,0 +1,98 @@
+package util
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+func TestParsePortSpec(t *testing.T) {
+	tcp := func(numbers ...int) []Port {
+		var ports []Port
+		for _, n := range numbers {
+			ports = append(ports, Port{Number: n, Proto: "tcp"})
+		}
+		return ports
+	}
+	udp := func(numbers ...int) []Port {
+		var ports []Port
+		for _, n := range numbers {
+			ports = append(ports, Port{Number: n, Proto: "udp"})
+		}
+		return ports
+	}
+
+	tests := []struct {
+		spec string
+		want []Port
+	}{
+		{"443,22,80", tcp(22, 80, 443)},
+		{" 22 , 80-82 ", tcp(22, 80, 81, 82)},
+		{"ssh,https", tcp(22, 443)},
+		{"ssh,22", tcp(22)},
+		{"http-alt", tcp(8080)},
+		{"65530-", tcp(65530, 65531, 65532, 65533, 65534, 65535)},
+		{"-3", tcp(1, 2, 3)},
+		{"130-140,!135-139", tcp(130, 131, 132, 133, 134, 140)},
+		{"!22,20-23", tcp(20, 21, 23)},
+		{"T:22,80,U:53,161", append(tcp(22, 80), udp(53, 161)...)},
+		{"U:domain,T:domain", append(tcp(53), udp(53)...)},
+		{"U:53,T:53,U:!53", tcp(53)},
+		{"80,22,80", tcp(22, 80)},
+		{"1-5,3,4-7", tcp(1, 2, 3, 4, 5, 6, 7)},
+		{"130-140,!135-139,!137", tcp(130, 131, 132, 133, 134, 140)},
+	}
+
+	logger := &VerboseLogger{}
+	for _, tt := range tests {
+		got, err := ParsePortSpec(tt.spec, logger)
+		if err != nil {
+			t.Errorf("ParsePortSpec(%q) error: %v", tt.spec, err)
+			continue
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("ParsePortSpec(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+
+	if got, err := ParsePortSpec("1-1000,443,ssh,https", logger); err != nil || len(got) != 1000 {
+		t.Errorf("ParsePortSpec(\"1-1000,443,ssh,https\") = %d ports, %v; want 1000", len(got), err)
+	}
+}
+
+func TestParsePortSpecErrors(t *testing.T) {
+	tests := []struct {
+		spec   string
+		offset int
+	}{
+		{"0", 0},
+		{"22,65536", 3},
+		{"22,99999999999999999999", 3},
+		{"100-90", 0},
+		{"22,,80", 3},
+		{"22,nosuchservice", 3},
+		{"22,top1000", 3},
+		{"22,80-9x", 7},
+		{"X:22", 0},
+		{"T:", 2},
+		{"22,U:!0", 6},
+		{"-", 0},
+	}
+
+	logger := &VerboseLogger{}
+	for _, tt := range tests {
+		_, err := ParsePortSpec(tt.spec, logger)
+		var specErr *PortSpecError
+		if !errors.As(err, &specErr) {
+			t.Errorf("ParsePortSpec(%q) error = %v, want a PortSpecError", tt.spec, err)
+			continue
+		}
+		if specErr.Offset != tt.offset {
+			t.Errorf("ParsePortSpec(%q) error at offset %d, want %d: %v", tt.spec, specErr.Offset, tt.offset, err)
+		}
+	}
+
+	if _, err := ParsePortSpec("22,!22", logger); err == nil {
+		t.Error("ParsePortSpec accepted a specification that leaves no ports")
+	}
+}
@@ -0,0 +1,68 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimiter is a token bucket shared by every probe goroutine in a scan.
+// The bucket holds a single token, so probes are spaced evenly instead of
+// being released in bursts. A nil *RateLimiter does not limit anything.
+type RateLimiter struct {
+	mu       sync.Mutex
+	interval time.Duration
+	next     time.Time
+}
+
+// NewRateLimiter allows at most rate probes per second with at least delay
+// between consecutive probes. Either may be zero; when both are, it returns
+// nil and Wait never blocks.
+func NewRateLimiter(rate float64, delay time.Duration) *RateLimiter {
+	interval := delay
+	if rate > 0 {
+		interval = max(interval, time.Duration(float64(time.Second)/rate))
+	}
+	if interval <= 0 {
+		return nil
+	}
+	return &RateLimiter{interval: interval}
+}
+
+// Wait blocks until the caller may send its next probe or ctx is done.
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	if r == nil {
+		return ctx.Err()
+	}
+
+	r.mu.Lock()
+	now := time.Now()
+	slot := r.next
+	if slot.Before(now) {
+		slot = now
+	}
+	r.next = slot.Add(r.interval)
+	r.mu.Unlock()
+
+	wait := time.Until(slot)
+	if wait <= 0 {
+		return ctx.Err()
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+func (r *RateLimiter) Interval() time.Duration {
+	if r == nil {
+		return 0
+	}
+	return r.interval
+}
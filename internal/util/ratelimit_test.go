@@ -0,0 +1,59 @@
+package util
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNewRateLimiter(t *testing.T) {
+	if NewRateLimiter(0, 0) != nil {
+		t.Error("NewRateLimiter(0, 0) should not limit")
+	}
+	if got := NewRateLimiter(100, 0).Interval(); got != 10*time.Millisecond {
+		t.Errorf("rate 100 interval = %v, want 10ms", got)
+	}
+	if got := NewRateLimiter(100, 50*time.Millisecond).Interval(); got != 50*time.Millisecond {
+		t.Errorf("scan delay should win over a faster rate, interval = %v", got)
+	}
+}
+
+func TestRateLimiterSharedAcrossGoroutines(t *testing.T) {
+	limiter := NewRateLimiter(200, 0)
+	ctx := context.Background()
+
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 5 {
+				limiter.Wait(ctx)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// 20 probes at 5ms spacing: the first goes immediately.
+	if elapsed := time.Since(start); elapsed < 19*5*time.Millisecond {
+		t.Errorf("20 probes at 200/s finished in %v", elapsed)
+	}
+}
+
+func TestRateLimiterCancel(t *testing.T) {
+	limiter := NewRateLimiter(0, time.Hour)
+	limiter.Wait(context.Background())
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err == nil {
+		t.Error("Wait() returned nil after the context expired")
+	}
+
+	var unlimited *RateLimiter
+	if err := unlimited.Wait(context.Background()); err != nil {
+		t.Errorf("nil limiter Wait() = %v", err)
+	}
+}
@@ -0,0 +1,73 @@
+package util
+
+import (
+	"sync"
+	"time"
+)
+
+// rttGranularity stands in for the clock granularity term of RFC 6298 so a
+// host with a perfectly steady RTT still gets some slack.
+const rttGranularity = 10 * time.Millisecond
+
+// RTTEstimator derives probe timeouts for one host from the round-trip times
+// of its answered probes, using the smoothed RTT and RTT variance that TCP
+// uses for its retransmission timeout (RFC 6298).
+type RTTEstimator struct {
+	mu      sync.Mutex
+	srtt    time.Duration
+	rttvar  time.Duration
+	samples int
+	initial time.Duration
+	minimum time.Duration
+	maximum time.Duration
+}
+
+// NewRTTEstimator returns an estimator that hands out initial until the first
+// sample arrives. Timeouts are always kept within minimum and maximum; a zero
+// maximum leaves them unbounded above.
+func NewRTTEstimator(initial, minimum, maximum time.Duration) *RTTEstimator {
+	return &RTTEstimator{initial: initial, minimum: minimum, maximum: maximum}
+}
+
+// Observe records the round-trip time of an answered probe.
+func (e *RTTEstimator) Observe(rtt time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.samples == 0 {
+		e.srtt = rtt
+		e.rttvar = rtt / 2
+	} else {
+		delta := e.srtt - rtt
+		if delta < 0 {
+			delta = -delta
+		}
+		e.rttvar = (3*e.rttvar + delta) / 4
+		e.srtt = (7*e.srtt + rtt) / 8
+	}
+	e.samples++
+}
+
+// Timeout returns how long the next probe should wait for an answer.
+func (e *RTTEstimator) Timeout() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	timeout := e.initial
+	if e.samples > 0 {
+		timeout = e.srtt + max(rttGranularity, 4*e.rttvar)
+	}
+
+	timeout = max(timeout, e.minimum)
+	if e.maximum > 0 {
+		timeout = min(timeout, e.maximum)
+	}
+	return timeout
+}
+
+// SRTT returns the smoothed round-trip time, or zero before any sample.
+func (e *RTTEstimator) SRTT() time.Duration {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.srtt
+}
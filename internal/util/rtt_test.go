@@ -0,0 +1,47 @@
+package util
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRTTEstimator(t *testing.T) {
+	e := NewRTTEstimator(500*time.Millisecond, 0, 0)
+	if got := e.Timeout(); got != 500*time.Millisecond {
+		t.Errorf("Timeout() before samples = %v, want the initial 500ms", got)
+	}
+
+	e.Observe(100 * time.Millisecond)
+	// SRTT 100ms, RTTVAR 50ms: 100ms + 4*50ms.
+	if got := e.Timeout(); got != 300*time.Millisecond {
+		t.Errorf("Timeout() after one sample = %v, want 300ms", got)
+	}
+
+	for range 50 {
+		e.Observe(20 * time.Millisecond)
+	}
+	if got := e.Timeout(); got > 40*time.Millisecond {
+		t.Errorf("Timeout() after a steady 20ms = %v, want it to converge near 30ms", got)
+	}
+	if got := e.SRTT(); got < 20*time.Millisecond || got > 22*time.Millisecond {
+		t.Errorf("SRTT() = %v, want about 20ms", got)
+	}
+}
+
+func TestRTTEstimatorBounds(t *testing.T) {
+	e := NewRTTEstimator(50*time.Millisecond, 100*time.Millisecond, time.Second)
+	if got := e.Timeout(); got != 100*time.Millisecond {
+		t.Errorf("Timeout() = %v, want the initial value raised to the 100ms minimum", got)
+	}
+
+	e.Observe(time.Millisecond)
+	if got := e.Timeout(); got != 100*time.Millisecond {
+		t.Errorf("Timeout() for a 1ms host = %v, want the 100ms minimum", got)
+	}
+
+	e = NewRTTEstimator(time.Second, 0, 2*time.Second)
+	e.Observe(3 * time.Second)
+	if got := e.Timeout(); got != 2*time.Second {
+		t.Errorf("Timeout() for a 3s host = %v, want the 2s maximum", got)
+	}
+}